@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"github.com/jeff-bruemmer/vaporwair/src/storage"
+	"os"
+	"strconv"
+)
+
+// LocationFlags hold a location given on the command line,
+// as coordinates, a ZIP code, or the name of a saved place or city.
+type LocationFlags struct {
+	Latitude  float64
+	Longitude float64
+	Zip       string
+	Place     string
+}
+
+// Register adds the -lat, -lon and -zip flags to fs.
+func (l *LocationFlags) Register(fs *flag.FlagSet) {
+	fs.Float64Var(&l.Latitude, "lat", 0, "Latitude of the location to report on, in decimal degrees. Requires -lon.")
+	fs.Float64Var(&l.Longitude, "lon", 0, "Longitude of the location to report on, in decimal degrees. Requires -lat.")
+	fs.StringVar(&l.Zip, "zip", "", "US ZIP code of the location to report on.")
+	fs.StringVar(&l.Place, "place", "", `Saved place or city to report on, e.g. "Portland, OR".`)
+}
+
+// Given reports whether a location was set on fs. The -lat and -lon flags
+// must be set together.
+func (l LocationFlags) Given(fs *flag.FlagSet) (bool, error) {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if set["lat"] != set["lon"] {
+		return false, errors.New("-lat and -lon must be given together")
+	}
+	return set["lat"] || l.Zip != "" || l.Place != "", nil
+}
+
+// Coordinates resolves the location. Place names are looked up in the saved
+// places, then in the gazetteer, asking the user to choose if the name is
+// ambiguous. ZIP codes missing from the gazetteer are looked up online,
+// unless the config file asks to stay offline.
+func (l LocationFlags) Coordinates(ctx context.Context, places storage.Places) (geolocation.Coordinates, error) {
+	switch {
+	case l.Place != "":
+		if c, err := places.Get(l.Place); err == nil {
+			return c, nil
+		}
+		matches := geolocation.Search(l.Place)
+		if len(matches) == 0 {
+			return geolocation.Coordinates{}, fmt.Errorf("%w %q", geolocation.ErrNoMatch, l.Place)
+		}
+		return ChoosePlace(matches).Coordinates(), nil
+	case l.Zip != "":
+		if c, ok := geolocation.LookupZip(l.Zip); ok {
+			return c, nil
+		}
+		if config.OfflineZip {
+			return geolocation.Coordinates{}, fmt.Errorf("%w: %s", geolocation.ErrUnknownZip, l.Zip)
+		}
+		return geolocation.GetZip(ctx, geolocation.ZipAddress, l.Zip)
+	}
+	c := geolocation.Coordinates{Latitude: l.Latitude, Longitude: l.Longitude}
+	return c, geolocation.Validate(c)
+}
+
+// ChoosePlace asks the user which of several places they meant.
+// Pressing enter picks the first, most populous, place.
+func ChoosePlace(matches []geolocation.Place) geolocation.Place {
+	if len(matches) == 1 {
+		return matches[0]
+	}
+	if len(matches) > 9 {
+		matches = matches[:9]
+	}
+	fmt.Println("Which place did you mean?")
+	for i, p := range matches {
+		fmt.Printf("%d) %s\n", i+1, p)
+	}
+	for {
+		answer := storage.Capture("Enter a number [1]: ")
+		if answer == "" {
+			return matches[0]
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(matches) {
+			return matches[n-1]
+		}
+	}
+}
+
+const locationsUsage = `Usage:
+  vaporwair locations [list]
+  vaporwair locations add <name> [-lat <lat> -lon <lon> | -zip <zip> | -place <city>]
+  vaporwair locations remove <name>
+  vaporwair locations default [<name>]
+
+Without -lat/-lon, -zip or -place, add saves your current location.
+Without a name, default clears the default place.`
+
+// Locations runs the locations subcommand, which manages saved places.
+func Locations(ctx context.Context, homeDir string, args []string) error {
+	path := homeDir + storage.PlacesFileName
+	places, err := storage.LoadPlaces(path)
+	if err != nil {
+		return err
+	}
+	cmd := "list"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	switch {
+	case cmd == "list" && len(args) == 0:
+		ListPlaces(places)
+		return nil
+	case cmd == "add" && len(args) > 0:
+		name := args[0]
+		var l LocationFlags
+		fs := flag.NewFlagSet("locations add", flag.ExitOnError)
+		l.Register(fs)
+		fs.Parse(args[1:])
+		given, err := l.Given(fs)
+		if err != nil {
+			LocationsUsage(err.Error())
+		}
+		var c geolocation.Coordinates
+		if given {
+			c, err = l.Coordinates(ctx, places)
+		} else {
+			c, err = GetCoordinates(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		if c.City == "" {
+			c.City = name
+		}
+		places.Places[name] = c
+		fmt.Println("Saved", name, "|", geolocation.FormatCoordinate(c.Latitude), ",", geolocation.FormatCoordinate(c.Longitude))
+	case cmd == "remove" && len(args) == 1:
+		if err := places.Remove(args[0]); err != nil {
+			return err
+		}
+	case cmd == "default" && len(args) <= 1:
+		places.Default = ""
+		if len(args) == 1 {
+			if _, err := places.Get(args[0]); err != nil {
+				return err
+			}
+			places.Default = args[0]
+		}
+	default:
+		LocationsUsage("")
+	}
+	return storage.SavePlaces(path, places)
+}
+
+// ListPlaces prints the saved places, marking the default.
+func ListPlaces(places storage.Places) {
+	if len(places.Places) == 0 {
+		fmt.Println("No saved places. Add one with: vaporwair locations add <name>")
+		return
+	}
+	for _, name := range places.Names() {
+		c := places.Places[name]
+		mark := " "
+		if name == places.Default {
+			mark = "*"
+		}
+		fmt.Println(mark, name, "|", geolocation.FormatCoordinate(c.Latitude), ",", geolocation.FormatCoordinate(c.Longitude), "|", c.City, c.Zip)
+	}
+}
+
+// LocationsUsage prints a problem with the command line and exits.
+func LocationsUsage(problem string) {
+	if problem != "" {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	fmt.Fprintln(os.Stderr, locationsUsage)
+	os.Exit(2)
+}
@@ -1,12 +1,11 @@
-// This package contains the data structures and facilities for retrieving forecasts
-// from the AirNow API
+// This package contains the data structures and facilities for retrieving
+// air quality forecasts and observations from AirNow and OpenAQ.
 package air
 
 import (
-	"encoding/json"
+	"context"
 	"github.com/jeff-bruemmer/vaporwair/src/dialer"
 	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
-	"log"
 )
 
 type Category struct {
@@ -14,6 +13,31 @@ type Category struct {
 	Name   string `json:"Name"`
 }
 
+// Categories are the EPA's AQI categories, numbered as AirNow numbers them.
+// Max is the highest AQI in each category.
+var Categories = []struct {
+	Category
+	Max int
+}{
+	{Category{1, "Good"}, 50},
+	{Category{2, "Moderate"}, 100},
+	{Category{3, "Unhealthy for Sensitive Groups"}, 150},
+	{Category{4, "Unhealthy"}, 200},
+	{Category{5, "Very Unhealthy"}, 300},
+	{Category{6, "Hazardous"}, 500},
+}
+
+// CategoryOf returns the category of an AQI. Values beyond the scale
+// are Hazardous.
+func CategoryOf(aqi int) Category {
+	for _, c := range Categories {
+		if aqi <= c.Max {
+			return c.Category
+		}
+	}
+	return Categories[len(Categories)-1].Category
+}
+
 type Forecast struct {
 	DateIssue     string   `json:"DateIssue"`
 	DateForecast  string   `json:"DateForecast"`
@@ -28,26 +52,68 @@ type Forecast struct {
 	Discussion    string   `json:"Discussion"`
 }
 
+// Observation is a pollutant's AQI measured in the last hour. AirNow
+// reports the hour in the reporting area's standard time.
+type Observation struct {
+	DateObserved  string   `json:"DateObserved"`
+	HourObserved  int      `json:"HourObserved"`
+	LocalTimeZone string   `json:"LocalTimeZone"`
+	ReportingArea string   `json:"ReportingArea"`
+	StateCode     string   `json:"StateCode"`
+	Latitude      float64  `json:"Latitude"`
+	Longitude     float64  `json:"Longitude"`
+	ParameterName string   `json:"ParameterName"`
+	AQI           int      `json:"AQI"`
+	Category      Category `json:"Category"`
+}
+
 const AirNowAddress = "http://www.airnowapi.org/aq/forecast/latLong/?format=application/json&"
 
+const AirNowObservationAddress = "http://www.airnowapi.org/aq/observation/latLong/current/?format=application/json&"
+
 // BuildAirNowURL creates http address for dialer to call Air Now API.
 func BuildAirNowURL(addr string, c geolocation.Coordinates, date string, apiKey string) string {
 	return addr +
-		"latitude=" + c.Latitude +
-		"&longitude=" + c.Longitude +
+		"latitude=" + geolocation.FormatCoordinate(c.Latitude) +
+		"&longitude=" + geolocation.FormatCoordinate(c.Longitude) +
 		"&date=" + date +
 		"&distance=25" +
 		"&API_KEY=" + apiKey
 }
 
+// BuildObservationURL creates the address of the current observations
+// near the coordinates.
+func BuildObservationURL(addr string, c geolocation.Coordinates, apiKey string) string {
+	return addr +
+		"latitude=" + geolocation.FormatCoordinate(c.Latitude) +
+		"&longitude=" + geolocation.FormatCoordinate(c.Longitude) +
+		"&distance=25" +
+		"&API_KEY=" + apiKey
+}
+
 // GetForecast dials AirNow API and returns a slice of Forecasts.
-func GetForecast(addr string) []Forecast {
+func GetForecast(ctx context.Context, addr string) ([]Forecast, error) {
 	var af []Forecast
-	resp, err := dialer.NetReq(addr, 10, false)
+	err := get(ctx, addr, &af)
+	return af, err
+}
+
+// GetObservations dials AirNow API and returns the current observations.
+func GetObservations(ctx context.Context, addr string) ([]Observation, error) {
+	var o []Observation
+	err := get(ctx, addr, &o)
+	return o, err
+}
+
+// get dials AirNow API and decodes the JSON response into v.
+func get(ctx context.Context, addr string, v interface{}) error {
+	resp, err := dialer.NetReq(ctx, addr, 10, false)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer resp.Body.Close()
-	json.NewDecoder(resp.Body).Decode(&af)
-	return af
+	if err := dialer.CheckStatus(resp, "AirNow API"); err != nil {
+		return err
+	}
+	return dialer.DecodeJSON(resp.Body, "AirNow API", v)
 }
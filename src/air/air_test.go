@@ -5,20 +5,25 @@ import (
 	"testing"
 )
 
-const exLatitude = "34.0308"
-const exLongitude = "-118.473"
+const exLatitude = 34.0308
+const exLongitude = -118.473
 const exDate = "Date"
 const exKey = "Key"
 const exCity = "City"
 const exZip = "Zip"
 
-var exCoordinates = geolocation.Coordinates{exLatitude, exLongitude, exCity, exZip}
+var exCoordinates = geolocation.Coordinates{
+	Latitude:  exLatitude,
+	Longitude: exLongitude,
+	City:      exCity,
+	Zip:       exZip,
+}
 
 func TestBuildAirNowURL(t *testing.T) {
 	got := BuildAirNowURL(AirNowAddress, exCoordinates, exDate, exKey)
 	answer := AirNowAddress +
-		"latitude=" + exCoordinates.Latitude +
-		"&longitude=" + exCoordinates.Longitude +
+		"latitude=34.0308" +
+		"&longitude=-118.473" +
 		"&date=" + exDate +
 		"&distance=25" +
 		"&API_KEY=" + exKey
@@ -26,3 +31,24 @@ func TestBuildAirNowURL(t *testing.T) {
 		t.Errorf("BuildAirNowURL(AirNowAddress, ex.Coordinates, exDate, exKey) = %s; want "+answer, got)
 	}
 }
+
+func TestBuildObservationURL(t *testing.T) {
+	got := BuildObservationURL(AirNowObservationAddress, exCoordinates, exKey)
+	answer := AirNowObservationAddress +
+		"latitude=34.0308" +
+		"&longitude=-118.473" +
+		"&distance=25" +
+		"&API_KEY=" + exKey
+	if got != answer {
+		t.Errorf("BuildObservationURL(AirNowObservationAddress, exCoordinates, exKey) = %s; want "+answer, got)
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	cases := map[int]string{0: "Good", 50: "Good", 51: "Moderate", 151: "Unhealthy", 301: "Hazardous", 999: "Hazardous"}
+	for aqi, want := range cases {
+		if got := CategoryOf(aqi).Name; got != want {
+			t.Errorf("CategoryOf(%d) = %s; want %s", aqi, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package air
+
+import (
+	"math"
+)
+
+// breakpoints are the concentrations at the top of each AQI category,
+// in the units of the EPA's AQI tables: µg/m³ for particles, ppm for ozone
+// and carbon monoxide, and ppb for nitrogen and sulfur dioxide. Ozone
+// above the 8-hour scale follows the 1-hour scale.
+var breakpoints = map[string]struct {
+	tops   [6]float64
+	digits int
+}{
+	"PM2.5": {[6]float64{9.0, 35.4, 55.4, 125.4, 225.4, 325.4}, 1},
+	"PM10":  {[6]float64{54, 154, 254, 354, 424, 604}, 0},
+	"O3":    {[6]float64{0.054, 0.070, 0.085, 0.105, 0.200, 0.604}, 3},
+	"CO":    {[6]float64{4.4, 9.4, 12.4, 15.4, 30.4, 50.4}, 1},
+	"NO2":   {[6]float64{53, 100, 360, 649, 1249, 2049}, 0},
+	"SO2":   {[6]float64{35, 75, 185, 304, 604, 1004}, 0},
+}
+
+// AQI converts a pollutant's concentration to the EPA's air quality index,
+// by interpolating between the breakpoints of its category. Concentrations
+// are in the units of the EPA's tables: µg/m³ for PM2.5 and PM10, ppm for
+// O3 and CO, and ppb for NO2 and SO2. It reports false for other pollutants
+// and negative concentrations. Concentrations beyond the scale are 500.
+func AQI(parameter string, concentration float64) (int, bool) {
+	b, ok := breakpoints[parameter]
+	if !ok || concentration < 0 {
+		return 0, false
+	}
+	// The EPA truncates concentrations to the precision of its tables.
+	// The epsilon keeps values such as 0.054 from flooring below themselves.
+	scale := math.Pow(10, float64(b.digits))
+	c := math.Floor(concentration*scale+1e-9) / scale
+	step := 1 / scale
+	low := 0.0
+	for i, high := range b.tops {
+		if c <= high {
+			aqiLow, aqiHigh := 0, Categories[i].Max
+			if i > 0 {
+				aqiLow = Categories[i-1].Max + 1
+			}
+			return int(math.Round(float64(aqiHigh-aqiLow)/(high-low)*(c-low))) + aqiLow, true
+		}
+		low = high + step
+	}
+	return Categories[len(Categories)-1].Max, true
+}
+
+// Molecular weights of gases, in g/mol, to convert µg/m³ to ppb.
+var molecularWeights = map[string]float64{
+	"O3":  48.00,
+	"NO2": 46.01,
+	"SO2": 64.07,
+	"CO":  28.01,
+}
+
+// ToEPAUnits converts a concentration in µg/m³, ppm or ppb to the units of
+// the EPA's tables for the pollutant. Gases are converted at 25 °C and one
+// atmosphere. It reports false for units it does not know.
+func ToEPAUnits(parameter string, v float64, units string) (float64, bool) {
+	ppb := 0.0
+	switch units {
+	case "µg/m³", "ug/m3", "μg/m³":
+		if parameter == "PM2.5" || parameter == "PM10" {
+			return v, true
+		}
+		mw, ok := molecularWeights[parameter]
+		if !ok {
+			return 0, false
+		}
+		ppb = v * 24.45 / mw
+	case "ppm":
+		ppb = v * 1000
+	case "ppb":
+		ppb = v
+	default:
+		return 0, false
+	}
+	switch parameter {
+	case "O3", "CO":
+		return ppb / 1000, true
+	case "NO2", "SO2":
+		return ppb, true
+	}
+	return 0, false
+}
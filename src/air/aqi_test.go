@@ -0,0 +1,57 @@
+package air
+
+import (
+	"testing"
+)
+
+func TestAQI(t *testing.T) {
+	cases := []struct {
+		parameter     string
+		concentration float64
+		want          int
+	}{
+		{"PM2.5", 0, 0},
+		{"PM2.5", 9.0, 50},
+		{"PM2.5", 9.09, 50},
+		{"PM2.5", 35.4, 100},
+		{"PM2.5", 35.5, 101},
+		{"PM2.5", 500, 500},
+		{"PM10", 154, 100},
+		{"O3", 0.0759, 115},
+		{"CO", 9.5, 101},
+		{"NO2", 101, 101},
+		{"SO2", 75.9, 100},
+	}
+	for _, c := range cases {
+		if got, ok := AQI(c.parameter, c.concentration); !ok || got != c.want {
+			t.Errorf("AQI(%s, %v) = %d, %v; want %d", c.parameter, c.concentration, got, ok, c.want)
+		}
+	}
+	if _, ok := AQI("PM1", 3); ok {
+		t.Error("AQI(PM1) is ok; want false")
+	}
+}
+
+func TestToEPAUnits(t *testing.T) {
+	cases := []struct {
+		parameter string
+		v         float64
+		units     string
+		want      float64
+	}{
+		{"PM2.5", 12, "µg/m³", 12},
+		{"O3", 0.05, "ppm", 0.05},
+		{"O3", 100, "µg/m³", 0.0509375},
+		{"NO2", 0.02, "ppm", 20},
+		{"CO", 1145.5, "µg/m³", 1.0},
+	}
+	for _, c := range cases {
+		got, ok := ToEPAUnits(c.parameter, c.v, c.units)
+		if !ok || got < c.want-1e-3 || got > c.want+1e-3 {
+			t.Errorf("ToEPAUnits(%s, %v, %s) = %v, %v; want %v", c.parameter, c.v, c.units, got, ok, c.want)
+		}
+	}
+	if _, ok := ToEPAUnits("PM2.5", 12, "ppm"); ok {
+		t.Error("ToEPAUnits(PM2.5, ppm) is ok; want false")
+	}
+}
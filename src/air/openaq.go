@@ -0,0 +1,232 @@
+package air
+
+import (
+	"context"
+	"github.com/jeff-bruemmer/vaporwair/src/dialer"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const OpenAQAddress = "https://api.openaq.org/v3/"
+
+// OpenAQ looks for stations within OpenAQRadius meters, the most it allows,
+// and asks the nearest OpenAQStations of them for their latest readings.
+const (
+	OpenAQRadius   = 25000
+	OpenAQStations = 3
+)
+
+// OpenAQMaxAge is how old a reading can be and still be reported as current.
+const OpenAQMaxAge = 3 * time.Hour
+
+// openAQParameters maps the OpenAQ names of the pollutants in the AQI
+// onto the names AirNow uses, in the order they are reported.
+var openAQParameters = []struct{ name, parameter string }{
+	{"o3", "O3"},
+	{"pm25", "PM2.5"},
+	{"pm10", "PM10"},
+	{"no2", "NO2"},
+	{"co", "CO"},
+	{"so2", "SO2"},
+}
+
+// OpenAQ retrieves the latest measurements of the nearest monitoring stations
+// from OpenAQ, which collects them from governments around the world, and
+// converts them to the EPA's AQI. OpenAQ does not forecast air quality.
+// Readings older than MaxAge are left out, unless it is zero.
+type OpenAQ struct {
+	Address string
+	APIKey  string
+	MaxAge  time.Duration
+}
+
+type openAQLocations struct {
+	Results []openAQLocation `json:"results"`
+}
+
+type openAQLocation struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Locality string `json:"locality"`
+	Timezone string `json:"timezone"`
+	Country  struct {
+		Code string `json:"code"`
+	} `json:"country"`
+	Coordinates struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"coordinates"`
+	Sensors []struct {
+		ID        int `json:"id"`
+		Parameter struct {
+			Name  string `json:"name"`
+			Units string `json:"units"`
+		} `json:"parameter"`
+	} `json:"sensors"`
+	Distance float64 `json:"distance"`
+}
+
+type openAQLatest struct {
+	Results []openAQReading `json:"results"`
+}
+
+type openAQReading struct {
+	Datetime struct {
+		UTC   string `json:"utc"`
+		Local string `json:"local"`
+	} `json:"datetime"`
+	Value     float64 `json:"value"`
+	SensorsID int     `json:"sensorsId"`
+}
+
+// BuildOpenAQLocationsURL creates the address of the stations near the coordinates.
+func BuildOpenAQLocationsURL(addr string, c geolocation.Coordinates) string {
+	return addr + "locations?coordinates=" +
+		geolocation.FormatCoordinate(c.Latitude) + "," +
+		geolocation.FormatCoordinate(c.Longitude) +
+		"&radius=" + strconv.Itoa(OpenAQRadius) +
+		"&limit=100"
+}
+
+// GetForecast returns no forecasts; OpenAQ only publishes measurements.
+func (q OpenAQ) GetForecast(ctx context.Context, c geolocation.Coordinates, t time.Time) ([]Forecast, error) {
+	return nil, nil
+}
+
+// GetObservations returns the AQI of each pollutant measured by the nearest
+// station that measures it. There are none if no station is in range.
+func (q OpenAQ) GetObservations(ctx context.Context, c geolocation.Coordinates) ([]Observation, error) {
+	var l openAQLocations
+	if err := q.get(ctx, BuildOpenAQLocationsURL(q.Address, c), &l); err != nil {
+		return nil, err
+	}
+	stations := nearest(l.Results, OpenAQStations)
+
+	// Ask the stations for their latest readings at once.
+	latest := make([]openAQLatest, len(stations))
+	errs := make(chan error, len(stations))
+	for i, s := range stations {
+		go func(i int, s openAQLocation) {
+			errs <- q.get(ctx, q.Address+"locations/"+strconv.Itoa(s.ID)+"/latest", &latest[i])
+		}(i, s)
+	}
+	var err error
+	for range stations {
+		if e := <-errs; e != nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var o []Observation
+	for _, p := range openAQParameters {
+		for i, s := range stations {
+			if obs, ok := q.observe(s, latest[i], p.name, p.parameter); ok {
+				o = append(o, obs)
+				break
+			}
+		}
+	}
+	return o, nil
+}
+
+// nearest returns up to n of the stations that measure a pollutant in the AQI,
+// closest first.
+func nearest(locations []openAQLocation, n int) []openAQLocation {
+	var stations []openAQLocation
+	for _, l := range locations {
+		for _, s := range l.Sensors {
+			if openAQParameter(s.Parameter.Name) != "" {
+				stations = append(stations, l)
+				break
+			}
+		}
+	}
+	sort.SliceStable(stations, func(i, j int) bool {
+		return stations[i].Distance < stations[j].Distance
+	})
+	if len(stations) > n {
+		stations = stations[:n]
+	}
+	return stations
+}
+
+// openAQParameter returns the AirNow name of an OpenAQ pollutant,
+// or "" if it is not in the AQI.
+func openAQParameter(name string) string {
+	for _, p := range openAQParameters {
+		if p.name == name {
+			return p.parameter
+		}
+	}
+	return ""
+}
+
+// observe converts the station's latest reading of a pollutant to an
+// Observation, in the station's time zone.
+func (q OpenAQ) observe(s openAQLocation, latest openAQLatest, name, parameter string) (Observation, bool) {
+	for _, sensor := range s.Sensors {
+		if sensor.Parameter.Name != name {
+			continue
+		}
+		for _, r := range latest.Results {
+			if r.SensorsID != sensor.ID {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, r.Datetime.UTC)
+			if err != nil || (q.MaxAge > 0 && time.Since(t) > q.MaxAge) {
+				continue
+			}
+			v, ok := ToEPAUnits(parameter, r.Value, sensor.Parameter.Units)
+			if !ok {
+				continue
+			}
+			aqi, ok := AQI(parameter, v)
+			if !ok {
+				continue
+			}
+			if loc, err := time.LoadLocation(s.Timezone); s.Timezone != "" && err == nil {
+				t = t.In(loc)
+			} else if local, err := time.Parse(time.RFC3339, r.Datetime.Local); err == nil {
+				t = local
+			}
+			area := s.Locality
+			if area == "" {
+				area = s.Name
+			}
+			return Observation{
+				DateObserved:  t.Format("2006-01-02"),
+				HourObserved:  t.Hour(),
+				LocalTimeZone: t.Format("MST"),
+				ReportingArea: area,
+				StateCode:     s.Country.Code,
+				Latitude:      s.Coordinates.Latitude,
+				Longitude:     s.Coordinates.Longitude,
+				ParameterName: parameter,
+				AQI:           aqi,
+				Category:      CategoryOf(aqi),
+			}, true
+		}
+	}
+	return Observation{}, false
+}
+
+// get dials the OpenAQ API with the API key and decodes the JSON response into v.
+func (q OpenAQ) get(ctx context.Context, addr string, v interface{}) error {
+	header := http.Header{}
+	header.Set("X-API-Key", q.APIKey)
+	resp, err := dialer.NetReqHeader(ctx, addr, 10, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := dialer.CheckStatus(resp, "OpenAQ API"); err != nil {
+		return err
+	}
+	return dialer.DecodeJSON(resp.Body, "OpenAQ API", v)
+}
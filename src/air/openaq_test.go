@@ -0,0 +1,67 @@
+package air
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAQGetObservations(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != exKey {
+			t.Errorf("request did not send the API key: %s", r.URL)
+		}
+		switch {
+		case r.URL.Path == "/locations":
+			if r.URL.Query().Get("coordinates") != "52.52,13.405" {
+				t.Errorf("coordinates = %s", r.URL.Query().Get("coordinates"))
+			}
+			http.ServeFile(w, r, "testdata/openaq/locations.json")
+		case strings.HasSuffix(r.URL.Path, "/latest"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/locations/"), "/latest")
+			http.ServeFile(w, r, "testdata/openaq/latest-"+id+".json")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	q := OpenAQ{Address: ts.URL + "/", APIKey: exKey}
+	c := exCoordinates
+	c.Latitude, c.Longitude = 52.52, 13.405
+	o, err := q.GetObservations(context.Background(), c)
+	if err != nil {
+		t.Fatalf("GetObservations returned error: %v", err)
+	}
+	// Ozone only comes from the farther station, and PM2.5 from the nearer.
+	want := []struct {
+		parameter string
+		aqi       int
+		area      string
+	}{
+		{"O3", 44, "Berlin Grunewald"},
+		{"PM2.5", 57, "Berlin"},
+		{"NO2", 20, "Berlin"},
+	}
+	if len(o) != len(want) {
+		t.Fatalf("got %d observations; want %d: %+v", len(o), len(want), o)
+	}
+	for i, w := range want {
+		if o[i].ParameterName != w.parameter || o[i].AQI != w.aqi || o[i].ReportingArea != w.area {
+			t.Errorf("o[%d] = %s %d in %s; want %s %d in %s",
+				i, o[i].ParameterName, o[i].AQI, o[i].ReportingArea, w.parameter, w.aqi, w.area)
+		}
+	}
+	if obs := o[1]; obs.DateObserved != "2024-06-21" || obs.HourObserved != 13 || obs.LocalTimeZone != "CEST" ||
+		obs.StateCode != "DE" || obs.Category.Name != "Moderate" {
+		t.Errorf("o[1] = %+v", obs)
+	}
+
+	// Readings older than MaxAge are not current.
+	q.MaxAge = OpenAQMaxAge
+	if o, err := q.GetObservations(context.Background(), c); err != nil || len(o) != 0 {
+		t.Errorf("stale readings: GetObservations = %+v, %v; want none", o, err)
+	}
+}
@@ -0,0 +1,40 @@
+package air
+
+import (
+	"context"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"time"
+)
+
+// Provider is implemented by every air quality service vaporwair can report
+// from. Each provider maps its own API onto the Forecast and Observation
+// types so the reports do not need to know where the data came from.
+// Services that do not forecast air quality return no forecasts.
+type Provider interface {
+	GetForecast(ctx context.Context, c geolocation.Coordinates, t time.Time) ([]Forecast, error)
+	GetObservations(ctx context.Context, c geolocation.Coordinates) ([]Observation, error)
+}
+
+// Provider names accepted in the config file.
+const (
+	AirNowName = "airnow"
+	OpenAQName = "openaq"
+)
+
+// AirNow retrieves forecasts and observations from the EPA's AirNow API,
+// which covers the United States.
+type AirNow struct {
+	ForecastAddress    string
+	ObservationAddress string
+	APIKey             string
+}
+
+// GetForecast returns the forecasts for the day of t and after.
+func (a AirNow) GetForecast(ctx context.Context, c geolocation.Coordinates, t time.Time) ([]Forecast, error) {
+	return GetForecast(ctx, BuildAirNowURL(a.ForecastAddress, c, t.Format("2006-01-02"), a.APIKey))
+}
+
+// GetObservations returns the AQI observed in the last hour.
+func (a AirNow) GetObservations(ctx context.Context, c geolocation.Coordinates) ([]Observation, error) {
+	return GetObservations(ctx, BuildObservationURL(a.ObservationAddress, c, a.APIKey))
+}
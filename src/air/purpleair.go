@@ -0,0 +1,259 @@
+package air
+
+import (
+	"context"
+	"github.com/jeff-bruemmer/vaporwair/src/dialer"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const PurpleAirName = "purpleair"
+
+const PurpleAirAddress = "https://api.purpleair.com/v1/"
+
+// PurpleAirMaxAge is how long ago, in seconds, a sensor must have reported
+// to be included.
+const PurpleAirMaxAge = 3600
+
+// SensorRadius is how far, in kilometers, a sensor can be from the location
+// of the report.
+const SensorRadius = 25.0
+
+// purpleAirFields are the sensor fields requested from PurpleAir. The
+// EPA's correction uses the CF=1 PM2.5 channels and the sensor's humidity.
+var purpleAirFields = []string{"name", "latitude", "longitude", "last_seen", "humidity", "pm2.5_cf_1_a", "pm2.5_cf_1_b"}
+
+// Confidence in a sensor's reading, from how well its two laser counters,
+// channels A and B, agree.
+const (
+	HighConfidence   = "high"
+	MediumConfidence = "medium"
+	LowConfidence    = "low"
+)
+
+// Box bounds an area by its northwest and southeast corners.
+type Box struct {
+	NWLat float64 `json:"nwlat"`
+	NWLng float64 `json:"nwlng"`
+	SELat float64 `json:"selat"`
+	SELng float64 `json:"selng"`
+}
+
+// PurpleAirConfig selects PurpleAir sensors in the config file, either by
+// their sensor indexes or by the outdoor sensors within a box.
+type PurpleAirConfig struct {
+	APIKey  string `json:"apikey"`
+	Sensors []int  `json:"sensors,omitempty"`
+	Box     *Box   `json:"box,omitempty"`
+}
+
+// Configured reports whether sensors have been selected.
+func (c PurpleAirConfig) Configured() bool {
+	return len(c.Sensors) > 0 || c.Box != nil
+}
+
+// PurpleAir retrieves PM2.5 readings from low-cost PurpleAir sensors, which
+// can be much closer than regulatory monitors. Sensors are selected by index,
+// or else by Box.
+type PurpleAir struct {
+	Address string
+	APIKey  string
+	Sensors []int
+	Box     *Box
+}
+
+// Sensor is a PurpleAir sensor's latest PM2.5 reading. A and B are its raw
+// CF=1 channels in µg/m³; PM25 is their average corrected with the EPA's
+// US-wide formula, and AQI its index. Confidence says how well A and B agree.
+// Sensors with a single channel have low confidence.
+type Sensor struct {
+	Index      int
+	Name       string
+	Latitude   float64
+	Longitude  float64
+	LastSeen   float64
+	Humidity   float64
+	A          float64
+	B          *float64
+	PM25       float64
+	AQI        int
+	Category   Category
+	Confidence string
+}
+
+type purpleAirSensors struct {
+	Fields []string        `json:"fields"`
+	Data   [][]interface{} `json:"data"`
+}
+
+// BuildPurpleAirURL creates the address of the configured sensors' readings.
+func BuildPurpleAirURL(p PurpleAir) string {
+	addr := p.Address + "sensors?fields=" + strings.Join(purpleAirFields, ",") +
+		"&max_age=" + strconv.Itoa(PurpleAirMaxAge)
+	if len(p.Sensors) > 0 {
+		indexes := make([]string, len(p.Sensors))
+		for i, s := range p.Sensors {
+			indexes[i] = strconv.Itoa(s)
+		}
+		return addr + "&show_only=" + strings.Join(indexes, ",")
+	}
+	if p.Box != nil {
+		addr += "&location_type=0" +
+			"&nwlat=" + geolocation.FormatCoordinate(p.Box.NWLat) +
+			"&nwlng=" + geolocation.FormatCoordinate(p.Box.NWLng) +
+			"&selat=" + geolocation.FormatCoordinate(p.Box.SELat) +
+			"&selng=" + geolocation.FormatCoordinate(p.Box.SELng)
+	}
+	return addr
+}
+
+// GetSensors returns the latest readings of the sensors within SensorRadius
+// of the coordinates that reported in the last PurpleAirMaxAge seconds, with
+// both PM2.5 channels corrected. Sensors without a humidity reading cannot
+// be corrected and are left out.
+func (p PurpleAir) GetSensors(ctx context.Context, c geolocation.Coordinates) ([]Sensor, error) {
+	header := http.Header{}
+	header.Set("X-API-Key", p.APIKey)
+	resp, err := dialer.NetReqHeader(ctx, BuildPurpleAirURL(p), 10, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := dialer.CheckStatus(resp, "PurpleAir API"); err != nil {
+		return nil, err
+	}
+	var ps purpleAirSensors
+	if err := dialer.DecodeJSON(resp.Body, "PurpleAir API", &ps); err != nil {
+		return nil, err
+	}
+	return Nearby(ps.sensors(), c, SensorRadius), nil
+}
+
+// Nearby returns the sensors within radius kilometers of the coordinates.
+func Nearby(sensors []Sensor, c geolocation.Coordinates, radius float64) []Sensor {
+	var near []Sensor
+	for _, s := range sensors {
+		at := geolocation.Coordinates{Latitude: s.Latitude, Longitude: s.Longitude}
+		if geolocation.Distance(c, at) <= radius {
+			near = append(near, s)
+		}
+	}
+	return near
+}
+
+// sensors reads the rows of the response by their field names.
+func (ps purpleAirSensors) sensors() []Sensor {
+	var sensors []Sensor
+	for _, row := range ps.Data {
+		field := map[string]interface{}{}
+		for i, name := range ps.Fields {
+			if i < len(row) {
+				field[name] = row[i]
+			}
+		}
+		number := func(name string) (float64, bool) {
+			v, ok := field[name].(float64)
+			return v, ok
+		}
+		index, _ := number("sensor_index")
+		a, okA := number("pm2.5_cf_1_a")
+		rh, okRH := number("humidity")
+		if !okA || !okRH {
+			continue
+		}
+		s := Sensor{Index: int(index), A: a, Humidity: rh, Confidence: LowConfidence}
+		s.Name, _ = field["name"].(string)
+		s.Latitude, _ = number("latitude")
+		s.Longitude, _ = number("longitude")
+		s.LastSeen, _ = number("last_seen")
+		pm := a
+		if b, ok := number("pm2.5_cf_1_b"); ok {
+			s.B = &b
+			pm = (a + b) / 2
+			s.Confidence = ChannelConfidence(a, b)
+		}
+		s.PM25 = CorrectPM25(pm, rh)
+		s.AQI, _ = AQI("PM2.5", s.PM25)
+		s.Category = CategoryOf(s.AQI)
+		sensors = append(sensors, s)
+	}
+	return sensors
+}
+
+// CorrectPM25 applies the EPA's US-wide correction to a PurpleAir CF=1 PM2.5
+// reading, in µg/m³, at relative humidity rh in percent. Above 50 µg/m³ the
+// formula blends into the EPA's extension for wildfire smoke.
+func CorrectPM25(pa, rh float64) float64 {
+	var pm float64
+	switch {
+	case pa < 30:
+		pm = 0.524*pa - 0.0862*rh + 5.75
+	case pa < 50:
+		s := pa/20 - 1.5
+		pm = (0.786*s+0.524*(1-s))*pa - 0.0862*rh + 5.75
+	case pa < 210:
+		pm = 0.786*pa - 0.0862*rh + 5.75
+	case pa < 260:
+		s := pa/50 - 4.2
+		pm = (0.69*s+0.786*(1-s))*pa - 0.0862*rh*(1-s) + 2.966*s + 5.75*(1-s) + 8.84e-4*pa*pa*s
+	default:
+		pm = 2.966 + 0.69*pa + 8.84e-4*pa*pa
+	}
+	return math.Max(pm, 0)
+}
+
+// ChannelConfidence rates how well a sensor's channels agree. The EPA drops
+// readings whose channels differ by more than 5 µg/m³ and by more than 70%;
+// those have low confidence. Channels within 2 µg/m³ or 20% have high confidence.
+func ChannelConfidence(a, b float64) string {
+	diff := math.Abs(a - b)
+	relative := 0.0
+	if a+b > 0 {
+		relative = diff / ((a + b) / 2)
+	}
+	switch {
+	case diff <= 2 || relative <= 0.2:
+		return HighConfidence
+	case diff <= 5 || relative <= 0.7:
+		return MediumConfidence
+	}
+	return LowConfidence
+}
+
+// Hyperlocal is the PM2.5 AQI of the sensors around a location. Confidence
+// is that of the least certain sensor it is based on.
+type Hyperlocal struct {
+	PM25       float64
+	AQI        int
+	Category   Category
+	Sensors    int
+	Confidence string
+}
+
+// HyperlocalAQI averages the corrected PM2.5 of the sensors, leaving out
+// those with low confidence. It reports false if no sensor is left.
+func HyperlocalAQI(sensors []Sensor) (Hyperlocal, bool) {
+	var h Hyperlocal
+	sum := 0.0
+	h.Confidence = HighConfidence
+	for _, s := range sensors {
+		if s.Confidence == LowConfidence {
+			continue
+		}
+		sum += s.PM25
+		h.Sensors++
+		if s.Confidence == MediumConfidence {
+			h.Confidence = MediumConfidence
+		}
+	}
+	if h.Sensors == 0 {
+		return Hyperlocal{}, false
+	}
+	h.PM25 = sum / float64(h.Sensors)
+	h.AQI, _ = AQI("PM2.5", h.PM25)
+	h.Category = CategoryOf(h.AQI)
+	return h, true
+}
@@ -0,0 +1,90 @@
+package air
+
+import (
+	"context"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPurpleAirGetSensors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != exKey {
+			t.Errorf("request did not send the API key: %s", r.URL)
+		}
+		if got := r.URL.Query().Get("show_only"); got != "131075,98221" {
+			t.Errorf("show_only = %s; want 131075,98221", got)
+		}
+		http.ServeFile(w, r, "testdata/purpleair/sensors.json")
+	}))
+	defer ts.Close()
+
+	p := PurpleAir{Address: ts.URL + "/", APIKey: exKey, Sensors: []int{131075, 98221}}
+	portland := geolocation.Coordinates{Latitude: 45.48, Longitude: -122.65}
+	sensors, err := p.GetSensors(context.Background(), portland)
+	if err != nil {
+		t.Fatalf("GetSensors returned error: %v", err)
+	}
+	// The sensor without humidity is left out.
+	want := []struct {
+		name       string
+		confidence string
+		aqi        int
+	}{
+		{"Sellwood Backyard", HighConfidence, 42},
+		{"Westmoreland", MediumConfidence, 46},
+		{"Brooklyn Garage", LowConfidence, 74},
+		{"Indoor Kitchen", LowConfidence, 27},
+	}
+	if len(sensors) != len(want) {
+		t.Fatalf("got %d sensors; want %d: %+v", len(sensors), len(want), sensors)
+	}
+	for i, w := range want {
+		if s := sensors[i]; s.Name != w.name || s.Confidence != w.confidence || s.AQI != w.aqi {
+			t.Errorf("sensors[%d] = %s %s %d; want %s %s %d", i, s.Name, s.Confidence, s.AQI, w.name, w.confidence, w.aqi)
+		}
+	}
+	if s := sensors[0]; s.Index != 131075 || s.B == nil || *s.B != 9.8 || math.Abs(s.PM25-7.542) > 1e-9 {
+		t.Errorf("sensors[0] = %+v", s)
+	}
+
+	h, ok := HyperlocalAQI(sensors)
+	if !ok || h.Sensors != 2 || h.Confidence != MediumConfidence || h.AQI != 44 {
+		t.Errorf("HyperlocalAQI = %+v, %v; want 2 sensors, medium confidence, AQI 44", h, ok)
+	}
+	if _, ok := HyperlocalAQI(sensors[2:]); ok {
+		t.Error("HyperlocalAQI of low confidence sensors is ok; want false")
+	}
+
+	// Sensors far from the location of the report are left out.
+	paris := geolocation.Coordinates{Latitude: 48.8566, Longitude: 2.3522}
+	if sensors, err := p.GetSensors(context.Background(), paris); err != nil || len(sensors) != 0 {
+		t.Errorf("GetSensors in Paris = %d sensors, %v; want none", len(sensors), err)
+	}
+}
+
+func TestBuildPurpleAirURL(t *testing.T) {
+	p := PurpleAir{Address: PurpleAirAddress, Box: &Box{NWLat: 45.6, NWLng: -122.8, SELat: 45.4, SELng: -122.5}}
+	want := PurpleAirAddress + "sensors?fields=name,latitude,longitude,last_seen,humidity,pm2.5_cf_1_a,pm2.5_cf_1_b" +
+		"&max_age=3600&location_type=0&nwlat=45.6&nwlng=-122.8&selat=45.4&selng=-122.5"
+	if got := BuildPurpleAirURL(p); got != want {
+		t.Errorf("BuildPurpleAirURL = %s; want %s", got, want)
+	}
+}
+
+func TestCorrectPM25(t *testing.T) {
+	cases := []struct{ pa, rh, want float64 }{
+		{10, 40, 7.542},
+		{40, 50, 27.64},
+		{100, 50, 80.04},
+		{300, 50, 289.526},
+		{0, 90, 0},
+	}
+	for _, c := range cases {
+		if got := CorrectPM25(c.pa, c.rh); math.Abs(got-c.want) > 1e-3 {
+			t.Errorf("CorrectPM25(%v, %v) = %v; want %v", c.pa, c.rh, got, c.want)
+		}
+	}
+}
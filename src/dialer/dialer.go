@@ -2,24 +2,147 @@
 package dialer
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
 	"net/http"
 	"time"
 )
 
+// UserAgent identifies vaporwair to API providers. The National Weather Service
+// rejects requests that do not include one.
+const UserAgent = "vaporwair (github.com/jeff-bruemmer/vaporwair)"
+
+// Retry determines how failed requests are retried. Requests that time out
+// or receive a 5xx status are retried after a jittered exponential backoff:
+// a random wait of up to Base, then up to twice that, and so on, capped at Max.
+type Retry struct {
+	Attempts int
+	Base     time.Duration
+	Max      time.Duration
+}
+
+// DefaultRetry makes up to three attempts per request.
+var DefaultRetry = Retry{
+	Attempts: 3,
+	Base:     250 * time.Millisecond,
+	Max:      2 * time.Second,
+}
+
+// The client and its transport are shared by every request so connections
+// to the same API are reused. Timeouts are set per request with contexts.
+var client = &http.Client{
+	Transport: http.DefaultTransport.(*http.Transport).Clone(),
+}
+
+// cancelBody releases a request's timeout once its body has been read and closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 // NetReq returns an *http.Response, or times out after a specified duration.
-func NetReq(url string, s time.Duration, gzip bool) (*http.Response, error) {
-	t := time.Duration(s * time.Second)
-	c := http.Client{
-		Timeout: t,
-	}
-	req, _ := http.NewRequest("GET", url, nil)
+// Each attempt gets its own timeout, and retries follow DefaultRetry.
+// Cancelling ctx abandons the request, including any pending retries.
+// Timeouts are reported as ErrTimeout.
+func NetReq(ctx context.Context, url string, s time.Duration, gzip bool) (*http.Response, error) {
+	header := http.Header{}
 	// Dark Sky uses gzip
 	if gzip {
-		req.Header.Set("Accept-Encoding", "gzip")
+		header.Set("Accept-Encoding", "gzip")
+	}
+	return NetReqHeader(ctx, url, s, header)
+}
+
+// NetReqHeader is NetReq with request headers, such as Accept for APIs that
+// serve several formats.
+func NetReqHeader(ctx context.Context, url string, s time.Duration, header http.Header) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+	attempts := DefaultRetry.Attempts
+	if attempts < 1 {
+		attempts = 1
 	}
-	resp, err := c.Do(req)
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if werr := wait(ctx, DefaultRetry.backoff(attempt)); werr != nil {
+				return nil, werr
+			}
+		}
+		resp, err = try(ctx, url, s*time.Second, header)
+		if !retryable(ctx, resp, err) {
+			break
+		}
+		// Discard the failed response before trying again.
+		if resp != nil && attempt < attempts-1 {
+			resp.Body.Close()
+		}
+	}
+	return resp, err
+}
+
+// try makes a single attempt at a request.
+func try(ctx context.Context, url string, timeout time.Duration, header http.Header) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	resp, err := client.Do(req)
 	if err != nil {
+		cancel()
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
 		return nil, err
 	}
+	resp.Body = cancelBody{resp.Body, cancel}
 	return resp, nil
 }
+
+// retryable reports whether a request is worth trying again:
+// it timed out or the server failed, and the caller has not given up.
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return errors.Is(err, ErrTimeout)
+	}
+	return resp.StatusCode >= 500
+}
+
+// backoff returns a random wait before the given retry attempt.
+func (r Retry) backoff(attempt int) time.Duration {
+	d := r.Base << uint(attempt-1)
+	if d > r.Max || d <= 0 {
+		d = r.Max
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// wait sleeps for d, returning early with the context's error if it is cancelled.
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,120 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckStatusQuota(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	resp, err := NetReq(context.Background(), ts.URL, 5, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	err = CheckStatus(resp, "Test API")
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("CheckStatus = %v; want StatusError 429", err)
+	}
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("CheckStatus = %v; want ErrQuotaExceeded", err)
+	}
+}
+
+// withRetry swaps in a retry policy for the duration of a test.
+func withRetry(t *testing.T, r Retry) {
+	saved := DefaultRetry
+	DefaultRetry = r
+	t.Cleanup(func() { DefaultRetry = saved })
+}
+
+func TestNetReqTimeout(t *testing.T) {
+	withRetry(t, Retry{Attempts: 1})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	if _, err := NetReq(context.Background(), ts.URL, 1, false); !errors.Is(err, ErrTimeout) {
+		t.Errorf("NetReq = %v; want ErrTimeout", err)
+	}
+}
+
+func TestNetReqRetry(t *testing.T) {
+	withRetry(t, Retry{Attempts: 3, Base: time.Millisecond, Max: 5 * time.Millisecond})
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	resp, err := NetReq(context.Background(), ts.URL, 5, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 3 {
+		t.Errorf("NetReq = %s after %d calls; want 200 OK after 3", resp.Status, calls)
+	}
+}
+
+func TestNetReqNoRetryOnClientError(t *testing.T) {
+	withRetry(t, Retry{Attempts: 3, Base: time.Millisecond, Max: 5 * time.Millisecond})
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	resp, err := NetReq(context.Background(), ts.URL, 5, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls != 1 {
+		t.Errorf("NetReq made %d calls for a 401; want 1", calls)
+	}
+}
+
+func TestNetReqCancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := NetReq(ctx, ts.URL, 5, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("NetReq = %v; want context.Canceled", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("NetReq took %v to return after cancellation", time.Since(start))
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var v map[string]string
+	err := DecodeJSON(strings.NewReader("<html>"), "Test API", &v)
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Source != "Test API" {
+		t.Errorf("DecodeJSON = %v; want DecodeError from Test API", err)
+	}
+}
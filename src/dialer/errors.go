@@ -0,0 +1,67 @@
+package dialer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrTimeout is returned when an API does not respond in time.
+var ErrTimeout = errors.New("request timed out")
+
+// ErrQuotaExceeded is returned when an API rejects a request
+// because the rate limit or quota for the API key has been used up.
+var ErrQuotaExceeded = errors.New("API quota exceeded")
+
+// StatusError is returned when an API responds with an unsuccessful HTTP status.
+type StatusError struct {
+	Source     string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return e.Source + " returned " + e.Status
+}
+
+// Is reports rate limiting responses as ErrQuotaExceeded.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrQuotaExceeded && e.StatusCode == http.StatusTooManyRequests
+}
+
+// DecodeError is returned when an API response cannot be decoded.
+type DecodeError struct {
+	Source string
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("could not decode response from %s: %v", e.Source, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// CheckStatus returns a StatusError unless the response status is 200 OK.
+// The source names the API in error messages.
+func CheckStatus(resp *http.Response, source string) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return &StatusError{
+		Source:     source,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+}
+
+// DecodeJSON decodes a JSON response body into v, wrapping any failure in a DecodeError.
+func DecodeJSON(r io.Reader, source string, v interface{}) error {
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return &DecodeError{Source: source, Err: err}
+	}
+	return nil
+}
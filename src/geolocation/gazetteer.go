@@ -0,0 +1,285 @@
+package geolocation
+
+import (
+	_ "embed"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The gazetteer holds US ZIP code centroids and cities around the world,
+// so places can be found without a network connection. See gazetteer/gen.go
+// to rebuild it from GeoNames.
+var (
+	//go:embed gazetteer/cities.tsv
+	citiesTSV string
+	//go:embed gazetteer/zips.tsv
+	zipsTSV string
+)
+
+// ErrNoMatch is returned when no saved place or city matches a name.
+var ErrNoMatch = errors.New("no saved place or city named")
+
+// Place is a city in the gazetteer. Region and Country are codes,
+// such as OR and US.
+type Place struct {
+	Name        string
+	Region      string
+	RegionName  string
+	Country     string
+	CountryName string
+	Latitude    float64
+	Longitude   float64
+	Population  int
+}
+
+// Label names the place and its region, such as "Portland, OR"
+// or "London, England".
+func (p Place) Label() string {
+	region := p.RegionName
+	if p.Country == "US" {
+		region = p.Region
+	}
+	if region == "" || region == p.Name {
+		return p.Name
+	}
+	return p.Name + ", " + region
+}
+
+func (p Place) String() string {
+	return p.Label() + ", " + p.CountryName
+}
+
+// Coordinates returns the location of the place.
+func (p Place) Coordinates() Coordinates {
+	return Coordinates{Latitude: p.Latitude, Longitude: p.Longitude, City: p.Label()}
+}
+
+// zipPlace is a ZIP code centroid in the gazetteer.
+type zipPlace struct {
+	Place     string
+	State     string
+	Latitude  float64
+	Longitude float64
+}
+
+var (
+	loadOnce sync.Once
+	cities   []Place
+	zips     map[string]zipPlace
+)
+
+// rows splits a gazetteer file into fields, skipping comments.
+func rows(tsv string) [][]string {
+	var rs [][]string
+	for _, line := range strings.Split(tsv, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rs = append(rs, strings.Split(line, "\t"))
+	}
+	return rs
+}
+
+// load parses the embedded gazetteer. Rows that cannot be parsed are skipped.
+func load() {
+	for _, r := range rows(citiesTSV) {
+		if len(r) != 8 {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(r[5], 64)
+		lon, err2 := strconv.ParseFloat(r[6], 64)
+		pop, err3 := strconv.Atoi(r[7])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		cities = append(cities, Place{r[0], r[1], r[2], r[3], r[4], lat, lon, pop})
+	}
+	zips = map[string]zipPlace{}
+	for _, r := range rows(zipsTSV) {
+		if len(r) != 5 {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(r[3], 64)
+		lon, err2 := strconv.ParseFloat(r[4], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		zips[r[0]] = zipPlace{r[1], r[2], lat, lon}
+	}
+}
+
+// LookupZip returns the centroid of a US ZIP code from the gazetteer.
+// ok is false if the ZIP code is not in the gazetteer.
+func LookupZip(zip string) (c Coordinates, ok bool) {
+	loadOnce.Do(load)
+	z, ok := zips[strings.TrimSpace(zip)]
+	if !ok {
+		return c, false
+	}
+	c.Latitude = z.Latitude
+	c.Longitude = z.Longitude
+	c.City = z.Place + ", " + z.State
+	c.Zip = strings.TrimSpace(zip)
+	return c, true
+}
+
+// Search finds the cities matching a query such as "Portland" or
+// "Portland, OR". Text after a comma narrows the search to a region or
+// country, by code or by name. Slight misspellings are forgiven. Only the
+// closest matches are returned, most populous first; more than one means
+// the query is ambiguous.
+func Search(query string) []Place {
+	loadOnce.Do(load)
+	parts := strings.Split(query, ",")
+	name := normalize(parts[0])
+	if name == "" {
+		return nil
+	}
+	var qualifiers []string
+	for _, q := range parts[1:] {
+		if q = normalize(q); q != "" {
+			qualifiers = append(qualifiers, q)
+		}
+	}
+
+	best := noMatch
+	var matches []Place
+	for _, p := range cities {
+		score := match(name, normalize(p.Name))
+		if score == noMatch || score > best || !qualified(p, qualifiers) {
+			continue
+		}
+		if score < best {
+			best = score
+			matches = nil
+		}
+		matches = append(matches, p)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Population > matches[j].Population
+	})
+	return matches
+}
+
+// How closely a name matches a query, best first.
+const (
+	exactMatch = iota
+	prefixMatch
+	fuzzyMatch
+	noMatch
+)
+
+// match scores how closely name matches the query.
+func match(query, name string) int {
+	switch {
+	case query == name:
+		return exactMatch
+	case len(query) >= 3 && strings.HasPrefix(name, query):
+		return prefixMatch
+	}
+	// Allow one typo in short names, two in long ones.
+	edits := 0
+	switch n := len([]rune(query)); {
+	case n > 8:
+		edits = 2
+	case n > 4:
+		edits = 1
+	}
+	if edits > 0 && distance(query, name) <= edits {
+		return fuzzyMatch
+	}
+	return noMatch
+}
+
+// countryAliases are common names for countries that differ from GeoNames.
+var countryAliases = map[string]string{
+	"usa":                      "US",
+	"america":                  "US",
+	"united states of america": "US",
+	"uk":                       "GB",
+	"britain":                  "GB",
+	"great britain":            "GB",
+}
+
+// qualified reports whether every qualifier names the place's region or country.
+func qualified(p Place, qualifiers []string) bool {
+	for _, q := range qualifiers {
+		switch q {
+		case normalize(p.Region), normalize(p.RegionName),
+			normalize(p.Country), normalize(p.CountryName):
+		default:
+			if countryAliases[q] != p.Country {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// accents folds common accented letters so "Bogota" finds Bogotá.
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u", "ý", "y", "ß", "ss",
+)
+
+// abbreviations are expanded so "St. Louis" and "Saint Louis" match.
+var abbreviations = map[string]string{
+	"st":  "saint",
+	"ste": "sainte",
+	"ft":  "fort",
+	"mt":  "mount",
+}
+
+// normalize lowercases a name, folds accents, expands abbreviations,
+// and reduces punctuation to single spaces.
+func normalize(s string) string {
+	s = accents.Replace(strings.ToLower(s))
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		if long, ok := abbreviations[w]; ok {
+			words[i] = long
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// distance returns the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,120 @@
+//go:build ignore
+
+// gen rebuilds the gazetteer from GeoNames (https://www.geonames.org/) dumps:
+// a cities file such as cities15000.txt, admin1CodesASCII.txt, countryInfo.txt,
+// and US.txt from the postal code export. Every city in the cities file is
+// kept by default, so towns such as state capitals can be found by name.
+//
+//	go run gen.go -dir ~/Downloads/geonames
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var dir = flag.String("dir", ".", "Directory holding the GeoNames files.")
+var citiesFile = flag.String("cities", "cities15000.txt", "GeoNames cities file.")
+var population = flag.Int("population", 15000, "Smallest population of a city to include.")
+
+// read calls fn with the tab-separated fields of each line in a GeoNames file,
+// skipping comments.
+func read(name string, fn func([]string)) {
+	f, err := os.Open(filepath.Join(*dir, name))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), "#") {
+			continue
+		}
+		fn(strings.Split(s.Text(), "\t"))
+	}
+	if err := s.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// write creates a gazetteer file with a header comment.
+func write(name, header string, rows [][]string) {
+	f, err := os.Create(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "# "+header)
+	for _, r := range rows {
+		fmt.Fprintln(w, strings.Join(r, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	f.Close()
+}
+
+// coordinate trims a coordinate to four decimal places, about 10 meters.
+func coordinate(s string) string {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		log.Fatalf("bad coordinate %q", s)
+	}
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
+
+func main() {
+	flag.Parse()
+
+	countries := map[string]string{}
+	read("countryInfo.txt", func(f []string) {
+		countries[f[0]] = f[4]
+	})
+	regions := map[string]string{}
+	read("admin1CodesASCII.txt", func(f []string) {
+		regions[f[0]] = f[1]
+	})
+
+	type city struct {
+		row        []string
+		population int
+	}
+	var cities []city
+	read(*citiesFile, func(f []string) {
+		pop, _ := strconv.Atoi(f[14])
+		if pop < *population {
+			return
+		}
+		cc, region := f[8], f[10]
+		cities = append(cities, city{
+			row: []string{f[1], region, regions[cc+"."+region], cc, countries[cc],
+				coordinate(f[4]), coordinate(f[5]), f[14]},
+			population: pop,
+		})
+	})
+	sort.SliceStable(cities, func(i, j int) bool {
+		return cities[i].population > cities[j].population
+	})
+	var rows [][]string
+	for _, c := range cities {
+		rows = append(rows, c.row)
+	}
+	write("cities.tsv", "name\tregion\tregion name\tcountry\tcountry name\tlatitude\tlongitude\tpopulation", rows)
+
+	rows = nil
+	read("US.txt", func(f []string) {
+		rows = append(rows, []string{f[1], f[2], f[4], coordinate(f[9]), coordinate(f[10])})
+	})
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0] < rows[j][0]
+	})
+	write("zips.tsv", "zip\tplace\tstate\tlatitude\tlongitude", rows)
+}
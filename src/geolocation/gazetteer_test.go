@@ -0,0 +1,52 @@
+package geolocation
+
+import (
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Portland, OR", []string{"Portland, OR, United States"}},
+		{"portland, oregon", []string{"Portland, OR, United States"}},
+		{"Portland, Maine, USA", []string{"Portland, ME, United States"}},
+		// Ambiguous names return every match, most populous first.
+		{"Portland", []string{"Portland, OR, United States", "Portland, ME, United States"}},
+		{"London", []string{"London, England, United Kingdom", "London, Ontario, Canada"}},
+		{"London, UK", []string{"London, England, United Kingdom"}},
+		// Misspellings, accents and abbreviations.
+		{"Portlnd, OR", []string{"Portland, OR, United States"}},
+		{"Bogota", []string{"Bogotá, Colombia"}},
+		{"St. Paul", []string{"Saint Paul, MN, United States"}},
+		{"Atlantis", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range Search(tt.query) {
+			got = append(got, p.String())
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Search(%q) = %v; want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Search(%q) = %v; want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLookupZip(t *testing.T) {
+	c, ok := LookupZip("90210")
+	want := Coordinates{Latitude: 34.0901, Longitude: -118.4065, City: "Beverly Hills, CA", Zip: "90210"}
+	if !ok || c != want {
+		t.Errorf("LookupZip(90210) = %+v, %v; want %+v", c, ok, want)
+	}
+	if _, ok := LookupZip("00000"); ok {
+		t.Errorf("LookupZip(00000) found a ZIP code")
+	}
+}
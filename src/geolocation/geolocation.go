@@ -1,19 +1,18 @@
-// This package handles data from IPAPI requests, which uses IP addresses to
-// obtain geolocation coordinates.
+// This package finds the user's coordinates, by IP address with one of several
+// geolocation services, or by name in an embedded gazetteer.
 package geolocation
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/jeff-bruemmer/vaporwair/src/dialer"
-	"os"
+	"context"
+	"errors"
+	"math"
 	"strconv"
-	"strings"
 )
 
+// Coordinates locate the user in decimal degrees.
 type Coordinates struct {
-	Latitude  string
-	Longitude string
+	Latitude  float64
+	Longitude float64
 	City      string
 	Zip       string
 }
@@ -37,47 +36,70 @@ type GeoData struct {
 
 const IPAPIAddress = "http://ip-api.com/json"
 
-// trimCoordinates drops trailing zeroes following
-// conversion of coordinates from float64 to string
-func trimCoordinates(c string) string {
-	slice := strings.Split(c, "")
-	for i := len(slice) - 1; i > 0; i-- {
-		n := slice[i]
-		if n == "0" {
-			slice = slice[:i]
-		} else {
-			break
-		}
-	}
-	return strings.Join(slice, "")
+// DefaultMatchRadius is the distance in kilometers within which
+// two locations are considered the same place.
+const DefaultMatchRadius = 5.0
+
+// earthRadius is the mean radius of the Earth in kilometers.
+const earthRadius = 6371.0
+
+// ErrNoLocation is returned when the geolocation service cannot resolve
+// coordinates for the user's IP address.
+var ErrNoLocation = errors.New("the geolocation service could not resolve your coordinates")
+
+// ErrInvalidCoordinates is returned for coordinates off the map.
+var ErrInvalidCoordinates = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+
+// FormatCoordinate formats a coordinate for API calls,
+// without trailing zeroes.
+func FormatCoordinate(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
 
 func FormatCoordinates(gd GeoData) Coordinates {
 	var c Coordinates
-	// Format coordinates for Forecast.io call
-	c.Latitude = trimCoordinates(strconv.FormatFloat(gd.Lat, 'f', 10, 64))
-	c.Longitude = trimCoordinates(strconv.FormatFloat(gd.Lon, 'f', 10, 64))
+	c.Latitude = gd.Lat
+	c.Longitude = gd.Lon
 	c.City = gd.City
 	c.Zip = gd.Zip
 	return c
 }
 
+// Validate checks that coordinates are on the map.
+func Validate(c Coordinates) error {
+	if math.Abs(c.Latitude) > 90 || math.Abs(c.Longitude) > 180 {
+		return ErrInvalidCoordinates
+	}
+	return nil
+}
+
+// Distance returns the great-circle distance between two locations
+// in kilometers, using the haversine formula.
+func Distance(a, b Coordinates) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Near reports whether two locations are within radius kilometers
+// of each other.
+func Near(a, b Coordinates, radius float64) bool {
+	return Distance(a, b) <= radius
+}
+
 // GetGeoData dials the IP-API server to obtain geolocation data
 // based on user's IP address.
-func GetGeoData(addr string) GeoData {
+func GetGeoData(ctx context.Context, addr string) (GeoData, error) {
 	var gd GeoData
-	// Request coordinates from ip-api and specify timeout in seconds
-	resp, err := dialer.NetReq(addr, 5, false)
-	if err != nil {
-		fmt.Println("The geolocation service could not resolve your coordinates.")
-		os.Exit(1)
+	if err := getJSON(ctx, addr, "IP-API", &gd); err != nil {
+		return gd, err
 	}
-	defer resp.Body.Close()
-	json.NewDecoder(resp.Body).Decode(&gd)
-
 	if gd.Status == "fail" {
-		fmt.Println("The geolocation service could not resolve your coordinates.")
-		os.Exit(1)
+		return gd, ErrNoLocation
 	}
-	return gd
+	return gd, nil
 }
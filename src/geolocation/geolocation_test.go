@@ -0,0 +1,67 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var santaMonica = Coordinates{Latitude: 34.0308, Longitude: -118.473}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		b    Coordinates
+		want float64
+	}{
+		{santaMonica, 0},
+		// Downtown Los Angeles.
+		{Coordinates{Latitude: 34.0522, Longitude: -118.2437}, 21.3},
+		// New York City.
+		{Coordinates{Latitude: 40.7128, Longitude: -74.006}, 3950},
+	}
+	for _, tt := range tests {
+		got := Distance(santaMonica, tt.b)
+		if math.Abs(got-tt.want) > tt.want*0.01+0.01 {
+			t.Errorf("Distance(%v, %v) = %.1f km; want about %.1f", santaMonica, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNear(t *testing.T) {
+	// IP geolocation often jitters in the last few decimal places.
+	jitter := Coordinates{Latitude: 34.03, Longitude: -118.47}
+	if !Near(santaMonica, jitter, DefaultMatchRadius) {
+		t.Errorf("Near(%v, %v) = false; want true", santaMonica, jitter)
+	}
+	pasadena := Coordinates{Latitude: 34.1478, Longitude: -118.1445}
+	if Near(santaMonica, pasadena, DefaultMatchRadius) {
+		t.Errorf("Near(%v, %v) = true; want false", santaMonica, pasadena)
+	}
+}
+
+func TestGetZip(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/90402" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"post code": "90402", "places": [{"place name": "Santa Monica",
+			"state abbreviation": "CA", "latitude": "34.0348", "longitude": "-118.503"}]}`))
+	}))
+	defer ts.Close()
+
+	c, err := GetZip(context.Background(), ts.URL+"/", "90402")
+	if err != nil {
+		t.Fatalf("GetZip returned error: %v", err)
+	}
+	want := Coordinates{Latitude: 34.0348, Longitude: -118.503, City: "Santa Monica, CA", Zip: "90402"}
+	if c != want {
+		t.Errorf("GetZip = %+v; want %+v", c, want)
+	}
+	if _, err := GetZip(context.Background(), ts.URL+"/", "00000"); !errors.Is(err, ErrUnknownZip) {
+		t.Errorf("GetZip for unknown ZIP returned %v; want ErrUnknownZip", err)
+	}
+}
@@ -0,0 +1,84 @@
+package geolocation
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const GPSDAddress = "localhost:2947"
+
+// DefaultGPSDTimeout is how long to wait for gpsd to report a fix.
+const DefaultGPSDTimeout = 2 * time.Second
+
+// ErrNoFix is returned when the GPS receiver does not have a fix in time.
+var ErrNoFix = errors.New("the GPS receiver has no fix")
+
+// GPSD finds the user with a GPS receiver served by gpsd
+// (https://gpsd.io/), using its JSON protocol.
+type GPSD struct {
+	Address string
+	Timeout time.Duration
+}
+
+// gpsdReport is a report from gpsd. Only TPV (time-position-velocity)
+// reports carry a position; Mode is 2 or 3 for a 2D or 3D fix.
+type gpsdReport struct {
+	Class string  `json:"class"`
+	Mode  int     `json:"mode"`
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+}
+
+// gpsdWatch asks gpsd to stream reports as JSON.
+const gpsdWatch = `?WATCH={"enable":true,"json":true};`
+
+// Locate watches gpsd until a TPV report with a fix arrives,
+// or returns ErrNoFix once the timeout passes.
+func (g GPSD) Locate(ctx context.Context) (Coordinates, error) {
+	timeout := g.Timeout
+	if timeout <= 0 {
+		timeout = DefaultGPSDTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", g.Address)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("gpsd: %w", err)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	// Unblock reads if the caller gives up.
+	go func() {
+		<-ctx.Done()
+		conn.SetDeadline(time.Now())
+	}()
+
+	if _, err := conn.Write([]byte(gpsdWatch)); err != nil {
+		return Coordinates{}, fmt.Errorf("gpsd: %w", err)
+	}
+	s := bufio.NewScanner(conn)
+	for s.Scan() {
+		var r gpsdReport
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			continue
+		}
+		if r.Class == "TPV" && r.Mode >= 2 {
+			return valid(Coordinates{Latitude: r.Lat, Longitude: r.Lon})
+		}
+	}
+	if ctx.Err() == context.Canceled {
+		return Coordinates{}, ctx.Err()
+	}
+	if ne, ok := s.Err().(net.Error); (ok && ne.Timeout()) || ctx.Err() != nil {
+		return Coordinates{}, ErrNoFix
+	}
+	return Coordinates{}, fmt.Errorf("gpsd closed the connection: %w", ErrNoFix)
+}
@@ -0,0 +1,76 @@
+package geolocation
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGPSD accepts one client, waits for it to start watching,
+// then sends the reports.
+func fakeGPSD(t *testing.T, reports ...string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte(`{"class":"VERSION","release":"3.22","proto_major":3,"proto_minor":14}` + "\n"))
+		watch, _ := bufio.NewReader(conn).ReadString(';')
+		if !strings.HasPrefix(watch, "?WATCH=") {
+			t.Errorf("client sent %q; want ?WATCH", watch)
+			return
+		}
+		for _, r := range reports {
+			conn.Write([]byte(r + "\n"))
+		}
+		// Stay connected, like gpsd waiting for a fix.
+		time.Sleep(time.Second)
+	}()
+	return ln.Addr().String()
+}
+
+func TestGPSD(t *testing.T) {
+	addr := fakeGPSD(t,
+		`{"class":"DEVICES","devices":[{"class":"DEVICE","path":"/dev/ttyACM0"}]}`,
+		`{"class":"TPV","device":"/dev/ttyACM0","mode":1}`,
+		`{"class":"TPV","device":"/dev/ttyACM0","mode":3,"lat":34.0308,"lon":-118.473,"alt":31.2}`,
+	)
+	c, err := GPSD{Address: addr}.Locate(context.Background())
+	if err != nil || c != santaMonica {
+		t.Errorf("Locate = %+v, %v; want %+v", c, err, santaMonica)
+	}
+}
+
+func TestGPSDNoFix(t *testing.T) {
+	addr := fakeGPSD(t, `{"class":"TPV","device":"/dev/ttyACM0","mode":1}`)
+	_, err := GPSD{Address: addr, Timeout: 100 * time.Millisecond}.Locate(context.Background())
+	if !errors.Is(err, ErrNoFix) {
+		t.Errorf("Locate without a fix returned %v; want ErrNoFix", err)
+	}
+}
+
+func TestGPSDFallback(t *testing.T) {
+	// Nothing listens on a closed listener's address.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	chain := Chain{Locators: []Locator{GPSD{Address: addr}, slow{0, santaMonica}}}
+	c, err := chain.Locate(context.Background())
+	if err != nil || c != santaMonica {
+		t.Errorf("Locate = %+v, %v; want the IP locator's %+v", c, err, santaMonica)
+	}
+}
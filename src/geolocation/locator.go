@@ -0,0 +1,195 @@
+package geolocation
+
+import (
+	"context"
+	"github.com/jeff-bruemmer/vaporwair/src/dialer"
+	"strconv"
+	"strings"
+)
+
+// Locator is implemented by every service that can find the user.
+// Cancelling the context abandons the lookup.
+type Locator interface {
+	Locate(ctx context.Context) (Coordinates, error)
+}
+
+// Locator names accepted in the config file.
+const (
+	IPAPIName   = "ipapi"
+	IPInfoName  = "ipinfo"
+	IPWhoisName = "ipwhois"
+)
+
+// DefaultLocators is the order IP geolocation services are tried in.
+var DefaultLocators = []string{IPAPIName, IPWhoisName, IPInfoName}
+
+const IPInfoAddress = "https://ipinfo.io/json"
+const IPWhoisAddress = "https://ipwho.is/"
+
+// IPAPI finds the user by IP address with the IP-API.
+type IPAPI struct {
+	Address string
+}
+
+func (l IPAPI) Locate(ctx context.Context) (Coordinates, error) {
+	gd, err := GetGeoData(ctx, l.Address)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	return valid(FormatCoordinates(gd))
+}
+
+// IPInfo finds the user by IP address with ipinfo.io. The free tier
+// works without a token, but with a lower rate limit.
+type IPInfo struct {
+	Address string
+	Token   string
+}
+
+// ipInfoData is the response from ipinfo.io. Loc holds "latitude,longitude".
+type ipInfoData struct {
+	City   string `json:"city"`
+	Region string `json:"region"`
+	Postal string `json:"postal"`
+	Loc    string `json:"loc"`
+	Bogon  bool   `json:"bogon"`
+}
+
+func (l IPInfo) Locate(ctx context.Context) (Coordinates, error) {
+	var c Coordinates
+	var d ipInfoData
+	addr := l.Address
+	if l.Token != "" {
+		addr += "?token=" + l.Token
+	}
+	if err := getJSON(ctx, addr, "ipinfo.io", &d); err != nil {
+		return c, err
+	}
+	loc := strings.Split(d.Loc, ",")
+	if d.Bogon || len(loc) != 2 {
+		return c, ErrNoLocation
+	}
+	lat, err := strconv.ParseFloat(loc[0], 64)
+	if err != nil {
+		return c, ErrNoLocation
+	}
+	lon, err := strconv.ParseFloat(loc[1], 64)
+	if err != nil {
+		return c, ErrNoLocation
+	}
+	c.Latitude = lat
+	c.Longitude = lon
+	c.City = d.City
+	c.Zip = d.Postal
+	return valid(c)
+}
+
+// IPWhois finds the user by IP address with ipwho.is.
+type IPWhois struct {
+	Address string
+}
+
+// ipWhoisData is the response from ipwho.is.
+type ipWhoisData struct {
+	Success   bool    `json:"success"`
+	City      string  `json:"city"`
+	Postal    string  `json:"postal"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func (l IPWhois) Locate(ctx context.Context) (Coordinates, error) {
+	var d ipWhoisData
+	if err := getJSON(ctx, l.Address, "ipwho.is", &d); err != nil {
+		return Coordinates{}, err
+	}
+	if !d.Success {
+		return Coordinates{}, ErrNoLocation
+	}
+	return valid(Coordinates{
+		Latitude:  d.Latitude,
+		Longitude: d.Longitude,
+		City:      d.City,
+		Zip:       d.Postal,
+	})
+}
+
+// Chain tries several locators. In order, each is tried until one finds
+// the user. Raced, all are asked at once and the first answer wins.
+// If every locator fails, the first locator's error is returned.
+type Chain struct {
+	Locators []Locator
+	Race     bool
+}
+
+func (ch Chain) Locate(ctx context.Context) (Coordinates, error) {
+	if len(ch.Locators) == 0 {
+		return Coordinates{}, ErrNoLocation
+	}
+	if ch.Race {
+		return ch.race(ctx)
+	}
+	var first error
+	for _, l := range ch.Locators {
+		c, err := l.Locate(ctx)
+		if err == nil {
+			return c, nil
+		}
+		if first == nil {
+			first = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return Coordinates{}, first
+}
+
+// race asks every locator at once, abandoning the rest once one answers.
+func (ch Chain) race(ctx context.Context) (Coordinates, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		i   int
+		c   Coordinates
+		err error
+	}
+	results := make(chan result, len(ch.Locators))
+	for i, l := range ch.Locators {
+		go func(i int, l Locator) {
+			c, err := l.Locate(ctx)
+			results <- result{i, c, err}
+		}(i, l)
+	}
+	errs := make([]error, len(ch.Locators))
+	for range ch.Locators {
+		r := <-results
+		if r.err == nil {
+			return r.c, nil
+		}
+		errs[r.i] = r.err
+	}
+	return Coordinates{}, errs[0]
+}
+
+// valid rejects coordinates off the map, and the null island
+// some services answer with when they cannot find an address.
+func valid(c Coordinates) (Coordinates, error) {
+	if Validate(c) != nil || (c.Latitude == 0 && c.Longitude == 0) {
+		return c, ErrNoLocation
+	}
+	return c, nil
+}
+
+// getJSON dials a geolocation service and decodes its response into v.
+func getJSON(ctx context.Context, addr, source string, v interface{}) error {
+	resp, err := dialer.NetReq(ctx, addr, 5, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := dialer.CheckStatus(resp, source); err != nil {
+		return err
+	}
+	return dialer.DecodeJSON(resp.Body, source, v)
+}
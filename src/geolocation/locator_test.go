@@ -0,0 +1,109 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"github.com/jeff-bruemmer/vaporwair/src/dialer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// standIn serves a canned geolocation response.
+func standIn(t *testing.T, status int, body string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestLocators(t *testing.T) {
+	tests := []struct {
+		name    string
+		locator func(addr string) Locator
+		body    string
+		want    Coordinates
+		wantErr error
+	}{
+		{"ip-api", func(a string) Locator { return IPAPI{Address: a} },
+			`{"status": "success", "city": "Santa Monica", "zip": "90402", "lat": 34.0308, "lon": -118.473}`,
+			santaMonica, nil},
+		{"ip-api fail", func(a string) Locator { return IPAPI{Address: a} },
+			`{"status": "fail", "message": "private range"}`,
+			Coordinates{}, ErrNoLocation},
+		{"ipinfo", func(a string) Locator { return IPInfo{Address: a} },
+			`{"ip": "192.0.2.1", "city": "Santa Monica", "postal": "90402", "loc": "34.0308,-118.4730"}`,
+			santaMonica, nil},
+		{"ipinfo bogon", func(a string) Locator { return IPInfo{Address: a} },
+			`{"ip": "10.0.0.1", "bogon": true}`,
+			Coordinates{}, ErrNoLocation},
+		{"ipwhois", func(a string) Locator { return IPWhois{Address: a} },
+			`{"success": true, "city": "Santa Monica", "postal": "90402", "latitude": 34.0308, "longitude": -118.473}`,
+			santaMonica, nil},
+		{"ipwhois null island", func(a string) Locator { return IPWhois{Address: a} },
+			`{"success": true, "latitude": 0, "longitude": 0}`,
+			Coordinates{}, ErrNoLocation},
+	}
+	for _, tt := range tests {
+		ts := standIn(t, http.StatusOK, tt.body)
+		c, err := tt.locator(ts.URL).Locate(context.Background())
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: Locate returned %v; want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		want := tt.want
+		want.City, want.Zip = "Santa Monica", "90402"
+		if err != nil || c != want {
+			t.Errorf("%s: Locate = %+v, %v; want %+v", tt.name, c, err, want)
+		}
+	}
+}
+
+// slow answers after a delay, or gives up when the lookup is abandoned.
+type slow struct {
+	delay time.Duration
+	c     Coordinates
+}
+
+func (s slow) Locate(ctx context.Context) (Coordinates, error) {
+	select {
+	case <-time.After(s.delay):
+		return s.c, nil
+	case <-ctx.Done():
+		return Coordinates{}, ctx.Err()
+	}
+}
+
+func TestChain(t *testing.T) {
+	// Fail fast rather than retrying the locator that is down.
+	retry := dialer.DefaultRetry
+	dialer.DefaultRetry = dialer.Retry{Attempts: 1}
+	defer func() { dialer.DefaultRetry = retry }()
+	down := IPAPI{Address: standIn(t, http.StatusServiceUnavailable, "").URL}
+	up := IPWhois{Address: standIn(t, http.StatusOK,
+		`{"success": true, "latitude": 34.0308, "longitude": -118.473}`).URL}
+	ctx := context.Background()
+
+	// In order, the chain falls back on the next locator.
+	c, err := Chain{Locators: []Locator{down, up}}.Locate(ctx)
+	if err != nil || c.Latitude != santaMonica.Latitude {
+		t.Errorf("Chain.Locate = %+v, %v; want %+v", c, err, santaMonica)
+	}
+	// If every locator fails, the first error is returned.
+	if _, err := (Chain{Locators: []Locator{down, down}}).Locate(ctx); err == nil {
+		t.Errorf("Chain.Locate of failing locators returned no error")
+	}
+
+	// Raced, the first answer wins even if it comes from the last locator.
+	far := Coordinates{Latitude: 40.7128, Longitude: -74.006}
+	race := Chain{Race: true, Locators: []Locator{down, slow{time.Second, far}, slow{0, santaMonica}}}
+	c, err = race.Locate(ctx)
+	if err != nil || c != santaMonica {
+		t.Errorf("raced Chain.Locate = %+v, %v; want %+v", c, err, santaMonica)
+	}
+}
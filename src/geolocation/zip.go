@@ -0,0 +1,65 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/dialer"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const ZipAddress = "https://api.zippopotam.us/us/"
+
+// ErrUnknownZip is returned when a ZIP code cannot be found.
+var ErrUnknownZip = errors.New("no such ZIP code")
+
+// zipData is the response from the Zippopotam.us API.
+// Coordinates are returned as strings.
+type zipData struct {
+	PostCode string `json:"post code"`
+	Places   []struct {
+		PlaceName         string `json:"place name"`
+		StateAbbreviation string `json:"state abbreviation"`
+		Latitude          string `json:"latitude"`
+		Longitude         string `json:"longitude"`
+	} `json:"places"`
+}
+
+// GetZip looks up the coordinates of a US ZIP code.
+func GetZip(ctx context.Context, addr string, zip string) (Coordinates, error) {
+	var c Coordinates
+	var zd zipData
+	resp, err := dialer.NetReq(ctx, addr+zip, 5, false)
+	if err != nil {
+		return c, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return c, fmt.Errorf("%w: %s", ErrUnknownZip, zip)
+	}
+	if err := dialer.CheckStatus(resp, "ZIP code service"); err != nil {
+		return c, err
+	}
+	if err := dialer.DecodeJSON(resp.Body, "ZIP code service", &zd); err != nil {
+		return c, err
+	}
+	if len(zd.Places) == 0 {
+		return c, fmt.Errorf("%w: %s", ErrUnknownZip, zip)
+	}
+	p := zd.Places[0]
+	lat, err := strconv.ParseFloat(strings.TrimSpace(p.Latitude), 64)
+	if err != nil {
+		return c, &dialer.DecodeError{Source: "ZIP code service", Err: err}
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(p.Longitude), 64)
+	if err != nil {
+		return c, &dialer.DecodeError{Source: "ZIP code service", Err: err}
+	}
+	c.Latitude = lat
+	c.Longitude = lon
+	c.City = p.PlaceName + ", " + p.StateAbbreviation
+	c.Zip = zd.PostCode
+	return c, nil
+}
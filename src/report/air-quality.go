@@ -4,28 +4,119 @@ import (
 	"fmt"
 	"github.com/jeff-bruemmer/vaporwair/src/air"
 	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"strconv"
+	"strings"
 )
 
-// AirQuality prints AQI levels for today and tomorrow.
+// AirQuality prints AQI levels for today and tomorrow, with the AQI
+// observed now beside today's forecast, then the readings of nearby sensors.
 // Includes O3, PM2.5, PM10, NO2, and CO indices.
-func AirQuality(w weather.Forecast, a []air.Forecast) {
-	fmt.Println(Title("Air Quality Forecast"))
-	format := "%s\t%v\t%v\t%s\n"
+func AirQuality(w weather.Forecast, a []air.Forecast, o []air.Observation, sensors []air.Sensor) {
+	fmt.Println(Paint(TitleStyle, Title("Air Quality Forecast")))
+	if len(o) > 0 {
+		fmt.Println("Now: observed at " + observedAt(o[0]) + ".")
+	}
+	format := "%s\t%v\t%s\t%v\t%s\n"
 	date := ""
-	fmt.Fprintf(TW, "Type\tAQI\tCategory\tDescription\n")
-	fmt.Fprintf(TW, "----\t---\t--------\t-----------\n")
+	fmt.Fprintf(TW, "Type\tAQI\t%s\tCategory\tDescription\n", PaintCell("", "Now"))
+	fmt.Fprintf(TW, "----\t---\t%s\t--------\t-----------\n", PaintCell("", "---"))
+	heading := func(d string) {
+		fmt.Println()
+		date = d
+		fmt.Println(date)
+		fmt.Println("==========")
+	}
+
+	// Observations are for today; pollutants observed but not forecast
+	// follow today's forecasts.
+	today := ""
+	if len(o) > 0 {
+		today = strings.TrimSpace(o[0].DateObserved)
+	}
+	observed := map[string]air.Observation{}
+	for _, obs := range o {
+		observed[obs.ParameterName] = obs
+	}
+	unforecast := func() {
+		for _, obs := range o {
+			if _, ok := observed[obs.ParameterName]; !ok {
+				continue
+			}
+			delete(observed, obs.ParameterName)
+			fmt.Fprintf(TW, format,
+				obs.ParameterName,
+				"-",
+				nowCell(obs),
+				obs.Category.Number,
+				Paint(AQIStyle(obs.Category.Number), obs.Category.Name))
+		}
+		TW.Flush()
+	}
+	if today != "" && (len(a) == 0 || strings.TrimSpace(a[0].DateForecast) != today) {
+		heading(today)
+		unforecast()
+	}
+
 	for _, f := range a {
 		if f.DateForecast != date {
-			fmt.Println()
-			date = f.DateForecast
-			fmt.Println(date)
-			fmt.Println("==========")
+			if today != "" && strings.TrimSpace(date) == today {
+				unforecast()
+			}
+			heading(f.DateForecast)
+		}
+		now := PaintCell("", "")
+		if obs, ok := observed[f.ParameterName]; ok && strings.TrimSpace(f.DateForecast) == today {
+			now = nowCell(obs)
+			delete(observed, f.ParameterName)
 		}
 		fmt.Fprintf(TW, format,
 			f.ParameterName,
 			f.AQI,
+			now,
 			f.Category.Number,
-			f.Category.Name)
+			Paint(AQIStyle(f.Category.Number), f.Category.Name))
 		TW.Flush()
 	}
+	if today != "" && strings.TrimSpace(date) == today {
+		unforecast()
+	}
+	Sensors(sensors)
+}
+
+// Sensors prints the PM2.5 readings of nearby sensors, corrected and converted
+// to AQI, with their raw channels and confidence, and the hyperlocal AQI.
+func Sensors(sensors []air.Sensor) {
+	if len(sensors) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("Nearby Sensors")
+	fmt.Println("==========")
+	if h, ok := air.HyperlocalAQI(sensors); ok {
+		aqi := fmt.Sprintf("%v PM2.5 %s", h.AQI, h.Category.Name)
+		fmt.Println("Hyperlocal: " + Paint(AQIStyle(h.Category.Number), aqi) + " (" + sensorCount(h) + ").")
+	} else {
+		fmt.Println("Hyperlocal: no sensor's channels agree.")
+	}
+	format := "%s\t%s\t%.1f\t%s\t%s\n"
+	fmt.Fprintf(TW, "Sensor\t%s\tPM2.5\tA/B\tConfidence\n", PaintCell("", "AQI"))
+	fmt.Fprintf(TW, "------\t%s\t-----\t---\t----------\n", PaintCell("", "---"))
+	for _, s := range sensors {
+		b := "-"
+		if s.B != nil {
+			b = fmt.Sprintf("%.1f", *s.B)
+		}
+		fmt.Fprintf(TW, format,
+			s.Name,
+			PaintCell(AQIStyle(s.Category.Number), strconv.Itoa(s.AQI)),
+			s.PM25,
+			fmt.Sprintf("%.1f/%s", s.A, b),
+			capitalize(s.Confidence))
+	}
+	TW.Flush()
+}
+
+// nowCell formats an observed AQI as a table cell.
+func nowCell(obs air.Observation) string {
+	return PaintCell(AQIStyle(obs.Category.Number), strconv.Itoa(obs.AQI))
 }
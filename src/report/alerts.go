@@ -0,0 +1,124 @@
+package report
+
+import (
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"strings"
+	"unicode/utf8"
+)
+
+// fresh holds the IDs of alerts shown for the first time.
+var fresh map[string]bool
+
+// SetNewAlerts highlights the alerts with the IDs, which have not been
+// shown before.
+func SetNewAlerts(ids map[string]bool) {
+	fresh = ids
+}
+
+// isNew reports whether an alert is shown for the first time.
+func isNew(a weather.Alert) bool {
+	return fresh[a.ID()]
+}
+
+// alertTitle marks new alerts.
+func alertTitle(a weather.Alert) string {
+	if isNew(a) {
+		return "NEW: " + a.Title
+	}
+	return a.Title
+}
+
+// alertStyle highlights new alerts.
+func alertStyle(a weather.Alert) string {
+	if isNew(a) {
+		return NewAlertStyle
+	}
+	return AlertStyle
+}
+
+// alertTime formats the time an alert was issued or expires,
+// such as Mon Jan 2 15:04 PDT.
+func alertTime(t float64) string {
+	return local(t).Format("Mon Jan 2 ") + FormatTime(t) + " " + Zone(t)
+}
+
+// AlertBanners prints a banner for each alert in effect, with when it expires.
+func AlertBanners(w weather.Forecast) {
+	for _, a := range w.Alerts {
+		s := alertTitle(a)
+		if a.Expires != 0 {
+			s += " until " + local(a.Expires).Format("Mon ") + FormatTime(a.Expires)
+		}
+		Banner(alertStyle(a), s)
+	}
+}
+
+// Alerts lists the weather alerts in effect, with their descriptions
+// wrapped to width columns.
+func Alerts(w weather.Forecast, width int) {
+	fmt.Println(Paint(TitleStyle, Title("Weather Alerts")))
+	if len(w.Alerts) == 0 {
+		fmt.Println("No weather alerts for this location.")
+		return
+	}
+	for i, a := range w.Alerts {
+		if i > 0 {
+			fmt.Println()
+		}
+		Banner(alertStyle(a), alertTitle(a))
+		if a.Severity != "" {
+			fmt.Fprintf(TW, f5, "Severity", capitalize(a.Severity))
+		}
+		if a.Urgency != "" {
+			fmt.Fprintf(TW, f5, "Urgency", capitalize(a.Urgency))
+		}
+		if a.Certainty != "" {
+			fmt.Fprintf(TW, f5, "Certainty", capitalize(a.Certainty))
+		}
+		if a.Time != 0 {
+			fmt.Fprintf(TW, f5, "Issued", alertTime(a.Time))
+		}
+		if a.Expires != 0 {
+			fmt.Fprintf(TW, f5, "Expires", alertTime(a.Expires))
+		}
+		if len(a.Regions) > 0 {
+			fmt.Fprintf(TW, f5, "Regions", strings.Join(a.Regions, ", "))
+		}
+		TW.Flush()
+		fmt.Println()
+		for _, line := range wrap(a.Description, width) {
+			fmt.Println(line)
+		}
+		if a.URI != "" {
+			fmt.Println(a.URI)
+		}
+	}
+}
+
+// wrap breaks text into lines of at most width columns. Paragraphs are
+// separated by blank lines, and words longer than a line are not broken.
+func wrap(s string, width int) []string {
+	var lines []string
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	for _, p := range strings.Split(s, "\n\n") {
+		words := strings.Fields(p)
+		if len(words) == 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line += " " + word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,22 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	s := "...WIND ADVISORY IN EFFECT...\n\n* WHAT...South winds\n20 to 30 mph.\r\n\r\n\n\n* WHERE...Portland."
+	want := []string{
+		"...WIND ADVISORY IN",
+		"EFFECT...",
+		"",
+		"* WHAT...South winds",
+		"20 to 30 mph.",
+		"",
+		"* WHERE...Portland.",
+	}
+	if got := wrap(s, 20); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrap = %q; want %q", got, want)
+	}
+}
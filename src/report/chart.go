@@ -0,0 +1,179 @@
+package report
+
+import (
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/air"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"math"
+	"strings"
+)
+
+// ChartHours is how far ahead the chart report looks.
+const ChartHours = 48
+
+// Chart markers.
+const (
+	dayMark     = '|'
+	sunriseMark = '↑'
+	sunsetMark  = '↓'
+)
+
+// blocks are the eighths of a character cell, from empty to full.
+var blocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// gutter is the width of the labels left of the chart.
+const gutter = 9
+
+// Chart draws the temperature and chance of precipitation over the next
+// 48 hours, fitted to width columns, with day boundaries and sunrise and
+// sunset marked.
+func Chart(w weather.Forecast, a []air.Forecast, width int) {
+	fmt.Println(Paint(TitleStyle, Title("48 Hour Chart")))
+	fmt.Println(AddPeriod(w.Hourly.Summary))
+	fmt.Println()
+	for _, line := range chart(w, width) {
+		fmt.Println(line)
+	}
+}
+
+// chartColumn is one or more hours of the chart, averaged.
+type chartColumn struct {
+	start, end  float64
+	temperature float64
+	chance      float64
+}
+
+// columns groups the hours so they fit in width columns.
+func columns(hours []weather.DataPoint, width int) []chartColumn {
+	if width < 1 {
+		width = 1
+	}
+	step := int(math.Ceil(float64(len(hours)) / float64(width)))
+	var cs []chartColumn
+	for i := 0; i < len(hours); i += step {
+		group := hours[i:minInt(i+step, len(hours))]
+		c := chartColumn{start: group[0].Time, end: group[len(group)-1].Time + 3600}
+		for _, h := range group {
+			c.temperature += h.Temperature / float64(len(group))
+			c.chance += ToPercent(h.PrecipProbability) / float64(len(group))
+		}
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// bars draws values as rows of block characters, top row first.
+// Each level is an eighth of a row.
+func bars(levels []int, height int) [][]rune {
+	rows := make([][]rune, height)
+	for r := range rows {
+		rows[r] = make([]rune, len(levels))
+		floor := (height - 1 - r) * 8
+		for i, l := range levels {
+			fill := l - floor
+			if fill < 0 {
+				fill = 0
+			} else if fill > 8 {
+				fill = 8
+			}
+			rows[r][i] = blocks[fill]
+		}
+	}
+	return rows
+}
+
+// paintRow styles each character of a row, a run of the same style at a time.
+func paintRow(row []rune, style func(i int) string) string {
+	var b strings.Builder
+	start := 0
+	for i := 1; i <= len(row); i++ {
+		if i < len(row) && style(i) == style(start) {
+			continue
+		}
+		b.WriteString(Paint(style(start), string(row[start:i])))
+		start = i
+	}
+	return b.String()
+}
+
+// gutterLabel right-aligns a label in the gutter.
+func gutterLabel(s string) string {
+	return fmt.Sprintf("%*s ", gutter-1, s)
+}
+
+// chart returns the lines of the chart.
+func chart(w weather.Forecast, width int) []string {
+	hours := LimitData(w.Hourly.Data, ChartHours)
+	if len(hours) == 0 {
+		return []string{"No hourly forecast."}
+	}
+	cs := columns(hours, width-gutter)
+
+	// Temperatures are scaled between the lowest and highest,
+	// so the lowest still shows.
+	const tempHeight = 4
+	lo, hi := cs[0].temperature, cs[0].temperature
+	for _, c := range cs {
+		lo = math.Min(lo, c.temperature)
+		hi = math.Max(hi, c.temperature)
+	}
+	span := math.Max(hi-lo, 1)
+	temps := make([]int, len(cs))
+	chances := make([]int, len(cs))
+	for i, c := range cs {
+		temps[i] = 1 + int(math.Round((c.temperature-lo)/span*(tempHeight*8-1)))
+		chances[i] = int(math.Ceil(c.chance / 100 * 2 * 8))
+	}
+	tempStyle := func(i int) string { return TempStyle(cs[i].temperature) }
+	chanceStyle := func(i int) string { return ChanceStyle(cs[i].chance) }
+
+	var lines []string
+	for r, row := range bars(temps, tempHeight) {
+		l := ""
+		switch r {
+		case 0:
+			l = fmt.Sprintf("%.0f %s", hi, tu)
+		case tempHeight - 1:
+			l = fmt.Sprintf("%.0f %s", lo, tu)
+		}
+		lines = append(lines, gutterLabel(l)+paintRow(row, tempStyle))
+	}
+	for r, row := range bars(chances, 2) {
+		l := ""
+		if r == 0 {
+			l = "Precip"
+		}
+		lines = append(lines, gutterLabel(l)+paintRow(row, chanceStyle))
+	}
+
+	// Mark midnights, sunrises and sunsets, and name each day.
+	marks := []rune(strings.Repeat(" ", len(cs)))
+	days := []rune(strings.Repeat(" ", len(cs)+3))
+	for i, c := range cs {
+		if i == 0 || local(c.start).Day() != local(cs[i-1].start).Day() {
+			if i > 0 {
+				marks[i] = dayMark
+			}
+			copy(days[i:], []rune(local(c.start).Format("Mon")))
+		}
+		for _, d := range w.Daily.Data {
+			if marks[i] == ' ' && d.SunriseTime >= c.start && d.SunriseTime < c.end {
+				marks[i] = sunriseMark
+			}
+			if marks[i] == ' ' && d.SunsetTime >= c.start && d.SunsetTime < c.end {
+				marks[i] = sunsetMark
+			}
+		}
+	}
+	lines = append(lines, gutterLabel("")+string(marks))
+	lines = append(lines, gutterLabel(Zone(cs[0].start))+strings.TrimRight(string(days), " "))
+	lines = append(lines, "", fmt.Sprintf("%c midnight  %c sunrise  %c sunset", dayMark, sunriseMark, sunsetMark))
+	return lines
+}
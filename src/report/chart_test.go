@@ -0,0 +1,50 @@
+package report
+
+import (
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestBars(t *testing.T) {
+	rows := bars([]int{0, 4, 8, 12, 16}, 2)
+	if got := string(rows[0]) + "/" + string(rows[1]); got != "   ▄█/ ▄███" {
+		t.Errorf("bars = %q", got)
+	}
+}
+
+func TestChart(t *testing.T) {
+	defer SetTimeZone(nil)
+	loc := time.FixedZone("PDT", -7*3600)
+	SetTimeZone(loc)
+	start := time.Date(2020, 6, 21, 18, 0, 0, 0, loc)
+	var w weather.Forecast
+	for i := 0; i < ChartHours; i++ {
+		w.Hourly.Data = append(w.Hourly.Data, weather.DataPoint{
+			Time:              float64(start.Add(time.Duration(i) * time.Hour).Unix()),
+			Temperature:       60 + float64(i%24),
+			PrecipProbability: 0.5,
+		})
+	}
+	w.Daily.Data = []weather.DataPoint{{
+		SunriseTime: float64(time.Date(2020, 6, 22, 5, 42, 0, 0, loc).Unix()),
+		SunsetTime:  float64(time.Date(2020, 6, 21, 20, 8, 0, 0, loc).Unix()),
+	}}
+
+	lines := chart(w, gutter+ChartHours)
+	marks := []rune(lines[6])[gutter:]
+	if len(marks) != ChartHours || marks[2] != sunsetMark || marks[6] != dayMark || marks[11] != sunriseMark {
+		t.Errorf("markers = %q; want sunset at 20:00, midnight and sunrise at 05:00", string(marks))
+	}
+	if !strings.HasPrefix(lines[7], "     PDT Sun   Mon") {
+		t.Errorf("days = %q", lines[7])
+	}
+
+	// Narrow terminals average hours into columns.
+	lines = chart(w, gutter+ChartHours/2)
+	if n := utf8.RuneCountInString(lines[0]); n != gutter+ChartHours/2 {
+		t.Errorf("narrow chart is %d columns wide; want %d", n, gutter+ChartHours/2)
+	}
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"time"
+)
+
+// tz is the time zone reports are shown in. If nil, times are shown
+// in the forecast's time zone.
+var tz *time.Location
+
+// Layouts for 24-hour and 12-hour clocks.
+const (
+	Clock24 = "15:04"
+	Clock12 = "3:04 PM"
+)
+
+var clock = Clock24
+
+// SetTimeZone shows times in loc, or in the forecast's time zone if loc is nil.
+func SetTimeZone(loc *time.Location) {
+	tz = loc
+}
+
+// SetClock shows times on a 12-hour clock, with AM and PM, or a 24-hour clock.
+func SetClock(hour12 bool) {
+	clock = Clock24
+	if hour12 {
+		clock = Clock12
+	}
+}
+
+// ForecastZone returns the forecast's time zone, falling back on its UTC
+// offset, or on the local time zone if the forecast has neither.
+func ForecastZone(w weather.Forecast) *time.Location {
+	if loc, err := time.LoadLocation(w.Timezone); err == nil && w.Timezone != "" {
+		return loc
+	}
+	if w.Timezone == "" && w.Offset == 0 {
+		return time.Local
+	}
+	return time.FixedZone("", int(w.Offset*3600))
+}
+
+// zone returns the time zone to show the forecast's times in.
+func zone(w weather.Forecast) *time.Location {
+	if tz != nil {
+		return tz
+	}
+	return ForecastZone(w)
+}
+
+// local converts a Unix time to the report's time zone.
+func local(t float64) time.Time {
+	loc := tz
+	if loc == nil {
+		loc = time.Local
+	}
+	return time.Unix(int64(t), 0).In(loc)
+}
+
+// Formats time
+func FormatTime(t float64) string {
+	return local(t).Format(clock)
+}
+
+// Zone abbreviates the time zone in effect at a time, such as PDT.
+func Zone(t float64) string {
+	return local(t).Format("MST")
+}
+
+// FormatDateTime formats a date and time, such as Mon Jan 2 15:04:05 MST 2006.
+func FormatDateTime(t time.Time) string {
+	if tz != nil {
+		t = t.In(tz)
+	}
+	if clock == Clock12 {
+		return t.Format("Mon Jan 2 3:04:05 PM MST 2006")
+	}
+	return t.Format("Mon Jan 2 15:04:05 MST 2006")
+}
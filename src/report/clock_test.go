@@ -0,0 +1,35 @@
+package report
+
+import (
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"testing"
+	"time"
+)
+
+func TestFormatTime(t *testing.T) {
+	defer SetTimeZone(nil)
+	defer SetClock(false)
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	sunrise := exForecast.Daily.Data[0].SunriseTime
+	SetTimeZone(ForecastZone(exForecast))
+	if got := FormatTime(sunrise) + " " + Zone(sunrise); got != "05:42 PDT" {
+		t.Errorf("sunrise in Los Angeles = %s; want 05:42 PDT", got)
+	}
+	SetTimeZone(paris)
+	SetClock(true)
+	if got := FormatTime(sunrise) + " " + Zone(sunrise); got != "2:42 PM CEST" {
+		t.Errorf("sunrise in Paris = %s; want 2:42 PM CEST", got)
+	}
+}
+
+func TestForecastZone(t *testing.T) {
+	if got := ForecastZone(weather.Forecast{Offset: -5}); got.String() == "Local" {
+		t.Error("ForecastZone ignored the forecast's UTC offset")
+	}
+	if got := ForecastZone(weather.Forecast{}); got != time.Local {
+		t.Errorf("ForecastZone of an empty forecast = %v; want local time", got)
+	}
+}
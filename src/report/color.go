@@ -0,0 +1,249 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrStyle is returned for a theme style that cannot be read.
+var ErrStyle = errors.New("unknown style")
+
+// Theme maps the names of report elements to styles, such as "bold red"
+// or "38;5;208". Styles are color and attribute names, or raw ANSI SGR
+// parameters; "none" leaves an element plain.
+type Theme map[string]string
+
+// Elements of the reports that can be styled.
+const (
+	TitleStyle         = "title"
+	AlertStyle         = "alert"
+	NewAlertStyle      = "newalert"
+	PrecipStyle        = "precip"
+	ColdStyle          = "cold"
+	CoolStyle          = "cool"
+	MildStyle          = "mild"
+	WarmStyle          = "warm"
+	HotStyle           = "hot"
+	GoodStyle          = "good"
+	ModerateStyle      = "moderate"
+	SensitiveStyle     = "sensitive"
+	UnhealthyStyle     = "unhealthy"
+	VeryUnhealthyStyle = "veryunhealthy"
+	HazardousStyle     = "hazardous"
+)
+
+// DefaultTheme colors AQI categories with the EPA's palette.
+var DefaultTheme = Theme{
+	TitleStyle:         "bold",
+	AlertStyle:         "bold bright-white on-red",
+	NewAlertStyle:      "bold bright-yellow on-red",
+	PrecipStyle:        "bold bright-cyan",
+	ColdStyle:          "bright-blue",
+	CoolStyle:          "cyan",
+	MildStyle:          "green",
+	WarmStyle:          "yellow",
+	HotStyle:           "bright-red",
+	GoodStyle:          "38;5;40",
+	ModerateStyle:      "38;5;226",
+	SensitiveStyle:     "38;5;208",
+	UnhealthyStyle:     "38;5;196",
+	VeryUnhealthyStyle: "38;5;97",
+	HazardousStyle:     "38;5;88",
+}
+
+// aqiStyles are the styles of the AQI categories, by number.
+var aqiStyles = []string{GoodStyle, ModerateStyle, SensitiveStyle, UnhealthyStyle, VeryUnhealthyStyle, HazardousStyle}
+
+// PrecipThreshold is the chance of precipitation, in percent, that is highlighted.
+const PrecipThreshold = 30
+
+var sgrCodes = map[string]int{
+	"bold": 1, "dim": 2, "italic": 3, "underline": 4, "reverse": 7,
+	"black": 30, "red": 31, "green": 32, "yellow": 33,
+	"blue": 34, "magenta": 35, "cyan": 36, "white": 37,
+}
+
+// sgr reads a style into ANSI SGR parameters. Colors can be prefixed with
+// bright- for the bright variant, and on- to set the background.
+func sgr(style string) (string, error) {
+	var params []string
+	for _, word := range strings.Fields(style) {
+		if word == "none" {
+			continue
+		}
+		if strings.Trim(word, "0123456789;") == "" {
+			params = append(params, word)
+			continue
+		}
+		offset := 0
+		name := word
+		if strings.HasPrefix(name, "on-") {
+			offset += 10
+			name = strings.TrimPrefix(name, "on-")
+		}
+		if strings.HasPrefix(name, "bright-") {
+			offset += 60
+			name = strings.TrimPrefix(name, "bright-")
+		}
+		code, ok := sgrCodes[name]
+		if !ok || (offset > 0 && code < 30) {
+			return "", fmt.Errorf("%w: %s", ErrStyle, word)
+		}
+		params = append(params, strconv.Itoa(code+offset))
+	}
+	return strings.Join(params, ";"), nil
+}
+
+// palette holds the SGR parameters of each style while colors are on.
+var palette map[string]string
+
+// SetColor turns colors on with a theme, or off if theme is nil. Styles
+// missing from the theme are taken from the default theme.
+func SetColor(theme Theme) error {
+	if theme == nil {
+		palette = nil
+		TW = newTabWriter(output)
+		return nil
+	}
+	p := map[string]string{}
+	for _, t := range []Theme{DefaultTheme, theme} {
+		for name, style := range t {
+			params, err := sgr(style)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			p[name] = params
+		}
+	}
+	palette = p
+	TW = newTabWriter(painter{output})
+	return nil
+}
+
+// Paint styles text printed outside of tables, or in the last column of one.
+func Paint(style, s string) string {
+	params := palette[style]
+	if params == "" {
+		return s
+	}
+	return "\x1b[" + params + "m" + s + "\x1b[0m"
+}
+
+// Table cells are styled with markers that take up the same width in every
+// cell of a column, so the tabwriter keeps the columns aligned. painter swaps
+// the markers for ANSI escapes once the table is laid out.
+const (
+	cellStart = '\uE000'
+	cellEnd   = '\uE0FF'
+)
+
+var cellStyles []string
+
+// PaintCell styles a table cell. Every cell of a column must be painted,
+// even if with no style, to keep the column aligned.
+func PaintCell(style, s string) string {
+	if palette == nil {
+		return s
+	}
+	i := indexOf(cellStyles, style)
+	if i < 0 {
+		i = len(cellStyles)
+		cellStyles = append(cellStyles, style)
+	}
+	return string(rune(cellStart+i)) + s + string(cellEnd)
+}
+
+func indexOf(s []string, v string) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// painter replaces cell markers with ANSI escapes.
+type painter struct {
+	w io.Writer
+}
+
+func (p painter) Write(b []byte) (int, error) {
+	var out strings.Builder
+	styled := false
+	for _, r := range string(b) {
+		switch {
+		case r == cellEnd:
+			if styled {
+				out.WriteString("\x1b[0m")
+				styled = false
+			}
+		case r >= cellStart && int(r-cellStart) < len(cellStyles):
+			if params := palette[cellStyles[r-cellStart]]; params != "" {
+				out.WriteString("\x1b[" + params + "m")
+				styled = true
+			}
+		default:
+			out.WriteRune(r)
+		}
+	}
+	if _, err := io.WriteString(p.w, out.String()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// TempStyle returns the style of a temperature in the report's units.
+func TempStyle(t float64) string {
+	f, _ := weather.Convert(t, tu, "°F")
+	switch {
+	case f < 32:
+		return ColdStyle
+	case f < 50:
+		return CoolStyle
+	case f < 70:
+		return MildStyle
+	case f < 85:
+		return WarmStyle
+	}
+	return HotStyle
+}
+
+// AQIStyle returns the style of an AQI category number.
+func AQIStyle(category int) string {
+	if category < 1 || category > len(aqiStyles) {
+		return ""
+	}
+	return aqiStyles[category-1]
+}
+
+// ChanceStyle highlights a likely chance of precipitation, in percent.
+func ChanceStyle(percent float64) string {
+	if percent >= PrecipThreshold {
+		return PrecipStyle
+	}
+	return ""
+}
+
+// Temperature formats a temperature with its unit and style.
+func Temperature(t float64) string {
+	return Paint(TempStyle(t), fmt.Sprintf("%.0f %s", Round(t), tu))
+}
+
+// TemperatureCell formats a temperature as a table cell.
+func TemperatureCell(t float64) string {
+	return PaintCell(TempStyle(t), fmt.Sprintf("%.0f %s", t, tu))
+}
+
+// ChanceCell formats a chance of precipitation, in percent, as a table cell.
+func ChanceCell(percent float64) string {
+	return PaintCell(ChanceStyle(percent), fmt.Sprintf("%.0f %s", percent, pc))
+}
+
+// Banner prints a line that stands out from the report, such as a weather alert.
+func Banner(style, s string) {
+	fmt.Fprintln(TW, Paint(style, " "+s+" "))
+}
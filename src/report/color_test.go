@@ -0,0 +1,50 @@
+package report
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSGR(t *testing.T) {
+	cases := map[string]string{
+		"bold bright-white on-red": "1;97;41",
+		"38;5;208":                 "38;5;208",
+		"none":                     "",
+	}
+	for style, want := range cases {
+		if got, err := sgr(style); err != nil || got != want {
+			t.Errorf("sgr(%q) = %q, %v; want %q", style, got, err, want)
+		}
+	}
+	if _, err := sgr("bright-bold"); err == nil {
+		t.Error("sgr accepted bright-bold")
+	}
+}
+
+func TestPaintCellAlignment(t *testing.T) {
+	defer SetColor(nil)
+	if err := SetColor(Theme{HotStyle: "red", MildStyle: "none"}); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	tw := newTabWriter(painter{&b})
+	tw.Write([]byte("Hour\t" + PaintCell("", "Temp") + "\tWind\n"))
+	tw.Write([]byte("13:00\t" + TemperatureCell(95) + "\t4 mph\n"))
+	tw.Write([]byte("14:00\t" + TemperatureCell(60) + "\t5 mph\n"))
+	tw.Flush()
+	if !strings.Contains(b.String(), "\x1b[31m95 °F\x1b[0m") {
+		t.Errorf("hot temperature not painted red: %q", b.String())
+	}
+	plain := regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(b.String(), "")
+	lines := strings.Split(plain, "\n")
+	column := func(line, s string) int {
+		return utf8.RuneCountInString(line[:strings.Index(line, s)])
+	}
+	wind := column(lines[0], "Wind")
+	if column(lines[1], "4 mph") != wind || column(lines[2], "5 mph") != wind {
+		t.Errorf("columns misaligned:\n%s", plain)
+	}
+}
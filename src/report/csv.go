@@ -0,0 +1,149 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Units of DataPoint fields not shown in the text reports.
+var au = "in"
+var bu = "°"
+var ou = "DU"
+
+// Column is a DataPoint field in CSV and TSV exports. Unit points at the
+// label for the field's values, if it has one.
+type Column struct {
+	Name  string
+	Unit  *string
+	Value func(d weather.DataPoint, loc *time.Location) string
+}
+
+// Header labels the column with its unit, such as "temperature (°F)".
+func (c Column) Header() string {
+	if c.Unit == nil {
+		return c.Name
+	}
+	return c.Name + " (" + *c.Unit + ")"
+}
+
+// Columns lists every DataPoint field, by its name in the Dark Sky schema.
+// Times are RFC 3339, and probabilities, humidity and cloud cover are
+// percentages. Fields the provider did not report are left empty.
+var Columns = []Column{
+	{"time", nil, timeOf(func(d weather.DataPoint) float64 { return d.Time })},
+	{"summary", nil, func(d weather.DataPoint, _ *time.Location) string { return d.Summary }},
+	{"icon", nil, func(d weather.DataPoint, _ *time.Location) string { return d.Icon }},
+	{"sunriseTime", nil, timeOf(func(d weather.DataPoint) float64 { return d.SunriseTime })},
+	{"sunsetTime", nil, timeOf(func(d weather.DataPoint) float64 { return d.SunsetTime })},
+	{"precipIntensity", &iu, number(func(d weather.DataPoint) float64 { return d.PrecipIntensity })},
+	{"precipIntensityMax", &iu, number(func(d weather.DataPoint) float64 { return d.PrecipIntensityMax })},
+	{"precipIntensityMaxTime", nil, timeOf(func(d weather.DataPoint) float64 { return d.PrecipIntensityMaxTime })},
+	{"precipProbability", &pc, percent(func(d weather.DataPoint) float64 { return d.PrecipProbability })},
+	{"precipType", nil, func(d weather.DataPoint, _ *time.Location) string { return d.PrecipType }},
+	{"precipAccumulation", &au, number(func(d weather.DataPoint) float64 { return d.PrecipAccumulation })},
+	{"temperature", &tu, number(func(d weather.DataPoint) float64 { return d.Temperature })},
+	{"temperatureMin", &tu, number(func(d weather.DataPoint) float64 { return d.TemperatureMin })},
+	{"temperatureMinTime", nil, timeOf(func(d weather.DataPoint) float64 { return d.TemperatureMinTime })},
+	{"temperatureMax", &tu, number(func(d weather.DataPoint) float64 { return d.TemperatureMax })},
+	{"temperatureMaxTime", nil, timeOf(func(d weather.DataPoint) float64 { return d.TemperatureMaxTime })},
+	{"apparentTemperature", &tu, number(func(d weather.DataPoint) float64 { return d.ApparentTemperature })},
+	{"dewPoint", &tu, number(func(d weather.DataPoint) float64 { return d.DewPoint })},
+	{"windSpeed", &wu, number(func(d weather.DataPoint) float64 { return d.WindSpeed })},
+	{"windBearing", &bu, number(func(d weather.DataPoint) float64 { return d.WindBearing })},
+	{"cloudCover", &pc, percent(func(d weather.DataPoint) float64 { return d.CloudCover })},
+	{"humidity", &pc, percent(func(d weather.DataPoint) float64 { return d.Humidity })},
+	{"pressure", &pu, number(func(d weather.DataPoint) float64 { return d.Pressure })},
+	{"visibility", &du, number(func(d weather.DataPoint) float64 { return d.Visibility })},
+	{"ozone", &ou, number(func(d weather.DataPoint) float64 { return d.Ozone })},
+	{"moonPhase", nil, number(func(d weather.DataPoint) float64 { return d.MoonPhase })},
+	{"uvIndex", nil, number(func(d weather.DataPoint) float64 { return d.UVIndex })},
+	{"uvIndexTime", nil, timeOf(func(d weather.DataPoint) float64 { return d.UVIndexTime })},
+	{"smoke", &su, optionalNumber(func(d weather.DataPoint) *float64 { return d.Smoke })},
+	{"fireIndex", nil, optionalNumber(func(d weather.DataPoint) *float64 { return d.FireIndex })},
+}
+
+// SelectColumns returns the named columns in order, or every column
+// if no names are given.
+func SelectColumns(names []string) ([]Column, error) {
+	if len(names) == 0 {
+		return Columns, nil
+	}
+	var cs []Column
+	for _, name := range names {
+		c, ok := column(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+func column(name string) (Column, bool) {
+	for _, c := range Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// Series writes hourly or daily data points with a header row. The comma
+// separates fields: ',' for CSV or '\t' for TSV.
+func Series(out io.Writer, w weather.Forecast, data []weather.DataPoint, columns []Column, comma rune) error {
+	loc := zone(w)
+	cw := csv.NewWriter(out)
+	cw.Comma = comma
+	row := make([]string, len(columns))
+	for i, c := range columns {
+		row[i] = c.Header()
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, d := range data {
+		for i, c := range columns {
+			row[i] = c.Value(d, loc)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func timeOf(field func(weather.DataPoint) float64) func(weather.DataPoint, *time.Location) string {
+	return func(d weather.DataPoint, loc *time.Location) string {
+		return timestamp(field(d), loc)
+	}
+}
+
+func number(field func(weather.DataPoint) float64) func(weather.DataPoint, *time.Location) string {
+	return func(d weather.DataPoint, _ *time.Location) string {
+		return strconv.FormatFloat(field(d), 'f', -1, 64)
+	}
+}
+
+// percent converts a fraction to a percentage, to two decimal places.
+func percent(field func(weather.DataPoint) float64) func(weather.DataPoint, *time.Location) string {
+	return func(d weather.DataPoint, _ *time.Location) string {
+		return strconv.FormatFloat(math.Round(field(d)*10000)/100, 'f', -1, 64)
+	}
+}
+
+func optionalNumber(field func(weather.DataPoint) *float64) func(weather.DataPoint, *time.Location) string {
+	return func(d weather.DataPoint, _ *time.Location) string {
+		v := reported(field(d))
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+}
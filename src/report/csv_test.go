@@ -0,0 +1,58 @@
+package report
+
+import (
+	"bytes"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestColumnsCoverDataPoint(t *testing.T) {
+	typ := reflect.TypeOf(weather.DataPoint{})
+	if typ.NumField() != len(Columns) {
+		t.Errorf("%d columns for %d DataPoint fields", len(Columns), typ.NumField())
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if _, ok := column(name); !ok {
+			t.Errorf("no column for %s", name)
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	cs, err := SelectColumns([]string{"time", "temperature", "precipProbability", "smoke"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := Series(&b, exForecast, exForecast.Hourly.Data, cs, ','); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,temperature (°F),precipProbability (%),smoke (µg/m³)\n" +
+		"2020-06-21T13:00:00-07:00,73.4,10,\n" +
+		"2020-06-21T14:00:00-07:00,75,0,\n"
+	if b.String() != want {
+		t.Errorf("Series =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	cs, _ = SelectColumns([]string{"summary", "humidity"})
+	data := []weather.DataPoint{{Summary: "Rain, then clear", Humidity: 0.615}}
+	if err := Series(&b, exForecast, data, cs, '\t'); err != nil {
+		t.Fatal(err)
+	}
+	if want := "summary\thumidity (%)\nRain, then clear\t61.5\n"; b.String() != want {
+		t.Errorf("TSV =\n%q\nwant\n%q", b.String(), want)
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	if cs, _ := SelectColumns(nil); len(cs) != len(Columns) {
+		t.Errorf("SelectColumns(nil) = %d columns; want all %d", len(cs), len(Columns))
+	}
+	if _, err := SelectColumns([]string{"time", "rainbows"}); err == nil {
+		t.Error("SelectColumns accepted an unknown column")
+	}
+}
@@ -0,0 +1,523 @@
+package report
+
+import (
+	"encoding/json"
+	"github.com/jeff-bruemmer/vaporwair/src/air"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"io"
+	"strings"
+	"time"
+)
+
+// JSONVersion is the version of the JSON document. It changes whenever
+// a field is removed or changes meaning; new fields may appear at any time.
+const JSONVersion = 1
+
+// Report names, as they appear in the JSON document.
+const (
+	SummaryReport  = "summary"
+	HourlyReport   = "hourly"
+	WeekReport     = "week"
+	AirReport      = "air"
+	ChartReport    = "chart"
+	MinutelyReport = "minutely"
+	AlertsReport   = "alerts"
+)
+
+// Document is the JSON form of a report. Only the sections belonging to the
+// report are filled in, and sections for a forecast that did not arrive are
+// left out; the source explains why. Times are RFC 3339 in the forecast's
+// time zone, and probabilities and humidity are percentages.
+type Document struct {
+	Version            int            `json:"version"`
+	Report             string         `json:"report"`
+	Generated          string         `json:"generated"`
+	Location           Location       `json:"location"`
+	Units              Units          `json:"units"`
+	Sources            []Source       `json:"sources"`
+	Alerts             *AlertsDoc     `json:"alerts,omitempty"`
+	Summary            *SummaryDoc    `json:"summary,omitempty"`
+	AirQuality         *AirDoc        `json:"airQuality,omitempty"`
+	ObservedAirQuality *ObservedDoc   `json:"observedAirQuality,omitempty"`
+	Hyperlocal         *HyperlocalDoc `json:"hyperlocal,omitempty"`
+	Minutely           *MinutelyDoc   `json:"minutely,omitempty"`
+	Hourly             *HourlyDoc     `json:"hourly,omitempty"`
+	Daily              *DailyDoc      `json:"daily,omitempty"`
+	Air                []AirDoc       `json:"air,omitempty"`
+	AirObserved        []ObservedDoc  `json:"airObserved,omitempty"`
+	Sensors            []SensorDoc    `json:"sensors,omitempty"`
+}
+
+// Location is where the forecasts are for.
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	City      string  `json:"city,omitempty"`
+	Zip       string  `json:"zip,omitempty"`
+	Timezone  string  `json:"timezone,omitempty"`
+}
+
+// Units labels the values in the document.
+type Units struct {
+	Temperature     string `json:"temperature"`
+	WindSpeed       string `json:"windSpeed"`
+	Pressure        string `json:"pressure"`
+	Visibility      string `json:"visibility"`
+	PrecipIntensity string `json:"precipIntensity"`
+	Smoke           string `json:"smoke"`
+	Percent         string `json:"percent"`
+}
+
+// units returns the labels of the values in the reports.
+func units() Units {
+	return Units{
+		Temperature:     tu,
+		WindSpeed:       wu,
+		Pressure:        pu,
+		Visibility:      du,
+		PrecipIntensity: iu,
+		Smoke:           su,
+		Percent:         pc,
+	}
+}
+
+// Source describes where a forecast came from. A forecast served from the
+// cache has the time it was saved and its age. Error explains a forecast
+// that did not arrive.
+type Source struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	CachedAt string `json:"cachedAt,omitempty"`
+	CacheAge int    `json:"cacheAgeSeconds,omitempty"`
+	Error    string `json:"error,omitempty"`
+	cachedAt time.Time
+}
+
+// Kinds of source.
+const (
+	WeatherSource     = "weather"
+	AirSource         = "air"
+	ObservationSource = "observation"
+	SensorSource      = "sensor"
+)
+
+// NewSource describes a forecast. cachedAt is zero for a fresh forecast,
+// and reason is empty if the forecast arrived.
+func NewSource(name, kind string, cachedAt time.Time, reason string) Source {
+	s := Source{Name: name, Kind: kind, Error: reason, cachedAt: cachedAt}
+	if !cachedAt.IsZero() {
+		s.CachedAt = cachedAt.Format(time.RFC3339)
+	}
+	return s
+}
+
+// available reports whether the forecast of a kind arrived.
+func available(sources []Source, kind string) bool {
+	for _, s := range sources {
+		if s.Kind == kind {
+			return s.Error == ""
+		}
+	}
+	return false
+}
+
+// AlertsDoc holds the weather alerts in effect. New is set for alerts
+// shown for the first time.
+type AlertsDoc struct {
+	Data []AlertDoc `json:"data"`
+}
+
+// AlertDoc is one weather alert.
+type AlertDoc struct {
+	Title       string   `json:"title"`
+	Severity    string   `json:"severity,omitempty"`
+	Urgency     string   `json:"urgency,omitempty"`
+	Certainty   string   `json:"certainty,omitempty"`
+	Regions     []string `json:"regions,omitempty"`
+	Issued      string   `json:"issued,omitempty"`
+	Expires     string   `json:"expires,omitempty"`
+	Description string   `json:"description,omitempty"`
+	URI         string   `json:"uri,omitempty"`
+	New         bool     `json:"new"`
+}
+
+// SummaryDoc holds the weather values of the Summary report. The report's
+// air quality index is the pollutant with the highest AQI today.
+type SummaryDoc struct {
+	Currently          string  `json:"currently,omitempty"`
+	ThisWeek           string  `json:"thisWeek,omitempty"`
+	Temperature        float64 `json:"temperature"`
+	TemperatureMin     float64 `json:"temperatureMin"`
+	TemperatureMinTime string  `json:"temperatureMinTime,omitempty"`
+	TemperatureMax     float64 `json:"temperatureMax"`
+	TemperatureMaxTime string  `json:"temperatureMaxTime,omitempty"`
+	Humidity           float64 `json:"humidity"`
+	WindSpeed          float64 `json:"windSpeed"`
+	UVIndex            float64 `json:"uvIndex"`
+	PrecipProbability  float64 `json:"precipProbability"`
+	PrecipType         string  `json:"precipType,omitempty"`
+	Sunrise            string  `json:"sunrise,omitempty"`
+	Sunset             string  `json:"sunset,omitempty"`
+}
+
+// MinutelyDoc holds the values of the Minutely report. Nowcast says when
+// precipitation starts or stops within the hour.
+type MinutelyDoc struct {
+	Summary string      `json:"summary,omitempty"`
+	Nowcast string      `json:"nowcast,omitempty"`
+	Data    []MinuteDoc `json:"data"`
+}
+
+// MinuteDoc is one minute of the forecast.
+type MinuteDoc struct {
+	Time              string  `json:"time"`
+	PrecipIntensity   float64 `json:"precipIntensity"`
+	PrecipProbability float64 `json:"precipProbability"`
+	PrecipType        string  `json:"precipType,omitempty"`
+}
+
+// HourlyDoc holds the values of the Hourly report.
+type HourlyDoc struct {
+	Summary string    `json:"summary,omitempty"`
+	Data    []HourDoc `json:"data"`
+}
+
+// HourDoc is one hour of the forecast. Smoke and fire index are only
+// reported by some providers.
+type HourDoc struct {
+	Time                string   `json:"time"`
+	Summary             string   `json:"summary,omitempty"`
+	Temperature         float64  `json:"temperature"`
+	ApparentTemperature float64  `json:"apparentTemperature"`
+	PrecipProbability   float64  `json:"precipProbability"`
+	PrecipIntensity     float64  `json:"precipIntensity"`
+	PrecipType          string   `json:"precipType,omitempty"`
+	Humidity            float64  `json:"humidity"`
+	WindSpeed           float64  `json:"windSpeed"`
+	WindBearing         float64  `json:"windBearing"`
+	Smoke               *float64 `json:"smoke,omitempty"`
+	FireIndex           *float64 `json:"fireIndex,omitempty"`
+}
+
+// DailyDoc holds the values of the Week report.
+type DailyDoc struct {
+	Summary string   `json:"summary,omitempty"`
+	Data    []DayDoc `json:"data"`
+}
+
+// DayDoc is one day of the forecast.
+type DayDoc struct {
+	Time               string  `json:"time"`
+	Summary            string  `json:"summary,omitempty"`
+	TemperatureMin     float64 `json:"temperatureMin"`
+	TemperatureMinTime string  `json:"temperatureMinTime,omitempty"`
+	TemperatureMax     float64 `json:"temperatureMax"`
+	TemperatureMaxTime string  `json:"temperatureMaxTime,omitempty"`
+	PrecipProbability  float64 `json:"precipProbability"`
+	PrecipType         string  `json:"precipType,omitempty"`
+	Humidity           float64 `json:"humidity"`
+	WindSpeed          float64 `json:"windSpeed"`
+	UVIndex            float64 `json:"uvIndex"`
+	Sunrise            string  `json:"sunrise,omitempty"`
+	Sunset             string  `json:"sunset,omitempty"`
+}
+
+// AirDoc is one pollutant's air quality forecast for a day.
+type AirDoc struct {
+	Date           string `json:"date"`
+	Parameter      string `json:"parameter"`
+	AQI            int    `json:"aqi"`
+	Category       string `json:"category"`
+	CategoryNumber int    `json:"categoryNumber"`
+	ReportingArea  string `json:"reportingArea,omitempty"`
+	ActionDay      bool   `json:"actionDay,omitempty"`
+}
+
+// ObservedDoc is one pollutant's AQI observed in the last hour. The hour is
+// in the reporting area's standard time.
+type ObservedDoc struct {
+	Date           string `json:"date"`
+	Hour           int    `json:"hour"`
+	TimeZone       string `json:"timeZone,omitempty"`
+	Parameter      string `json:"parameter"`
+	AQI            int    `json:"aqi"`
+	Category       string `json:"category"`
+	CategoryNumber int    `json:"categoryNumber"`
+	ReportingArea  string `json:"reportingArea,omitempty"`
+}
+
+// HyperlocalDoc is the PM2.5 AQI of the nearby sensors whose channels agree.
+// PM25 is in µg/m³.
+type HyperlocalDoc struct {
+	PM25           float64 `json:"pm25"`
+	AQI            int     `json:"aqi"`
+	Category       string  `json:"category"`
+	CategoryNumber int     `json:"categoryNumber"`
+	Sensors        int     `json:"sensors"`
+	Confidence     string  `json:"confidence"`
+}
+
+// SensorDoc is a nearby sensor's latest PM2.5 reading. PM25 is corrected
+// with the EPA's formula; A and B are the raw channels, in µg/m³.
+type SensorDoc struct {
+	Index          int      `json:"index"`
+	Name           string   `json:"name,omitempty"`
+	Latitude       float64  `json:"latitude"`
+	Longitude      float64  `json:"longitude"`
+	LastSeen       string   `json:"lastSeen,omitempty"`
+	PM25           float64  `json:"pm25"`
+	A              float64  `json:"a"`
+	B              *float64 `json:"b,omitempty"`
+	Humidity       float64  `json:"humidity"`
+	AQI            int      `json:"aqi"`
+	Category       string   `json:"category"`
+	CategoryNumber int      `json:"categoryNumber"`
+	Confidence     string   `json:"confidence"`
+}
+
+// JSON builds the document for a report. The sources decide which
+// sections can be filled in.
+func JSON(report string, w weather.Forecast, a []air.Forecast, o []air.Observation, sensors []air.Sensor, l Location, sources []Source, now time.Time) Document {
+	loc := zone(w)
+	l.Timezone = w.Timezone
+	d := Document{
+		Version:   JSONVersion,
+		Report:    report,
+		Generated: now.In(loc).Format(time.RFC3339),
+		Location:  l,
+		Units:     units(),
+		Sources:   sources,
+	}
+	for i, s := range d.Sources {
+		if !s.cachedAt.IsZero() {
+			d.Sources[i].CacheAge = int(now.Sub(s.cachedAt).Seconds())
+		}
+	}
+	hasWeather := available(sources, WeatherSource)
+	hasAir := available(sources, AirSource)
+	hasObserved := available(sources, ObservationSource)
+	hasSensors := available(sources, SensorSource)
+
+	if hasWeather && (report == SummaryReport || report == AlertsReport) {
+		d.Alerts = &AlertsDoc{Data: []AlertDoc{}}
+		for _, a := range w.Alerts {
+			d.Alerts.Data = append(d.Alerts.Data, alertDoc(a, loc))
+		}
+	}
+	switch report {
+	case SummaryReport:
+		if hasWeather && len(w.Daily.Data) > 0 && len(w.Hourly.Data) > 0 {
+			d.Summary = summaryDoc(w, loc)
+		}
+		if top, ok := topAQI(a); ok && hasAir {
+			doc := airDoc(top)
+			d.AirQuality = &doc
+		}
+		if now, ok := topObserved(o); ok && hasObserved {
+			doc := observedDoc(now)
+			d.ObservedAirQuality = &doc
+		}
+		if h, ok := air.HyperlocalAQI(sensors); ok && hasSensors {
+			d.Hyperlocal = hyperlocalDoc(h)
+		}
+	case MinutelyReport:
+		if hasWeather {
+			minutes := LimitData(w.Minutely.Data, MinutelyMinutes)
+			d.Minutely = &MinutelyDoc{Summary: w.Minutely.Summary, Nowcast: Nowcast(minutes), Data: []MinuteDoc{}}
+			for _, m := range minutes {
+				d.Minutely.Data = append(d.Minutely.Data, MinuteDoc{
+					Time:              timestamp(m.Time, loc),
+					PrecipIntensity:   m.PrecipIntensity,
+					PrecipProbability: ToPercent(m.PrecipProbability),
+					PrecipType:        m.PrecipType,
+				})
+			}
+		}
+	case HourlyReport, ChartReport:
+		hours := 12
+		if report == ChartReport {
+			hours = ChartHours
+		}
+		if hasWeather {
+			d.Hourly = &HourlyDoc{Summary: w.Hourly.Summary, Data: []HourDoc{}}
+			for _, h := range LimitData(w.Hourly.Data, hours) {
+				d.Hourly.Data = append(d.Hourly.Data, hourDoc(h, loc))
+			}
+		}
+	case WeekReport:
+		if hasWeather {
+			d.Daily = &DailyDoc{Summary: w.Daily.Summary, Data: []DayDoc{}}
+			for _, day := range LimitData(w.Daily.Data, 7) {
+				d.Daily.Data = append(d.Daily.Data, dayDoc(day, loc))
+			}
+		}
+	case AirReport:
+		if hasAir {
+			d.Air = []AirDoc{}
+			for _, f := range a {
+				d.Air = append(d.Air, airDoc(f))
+			}
+		}
+		if hasObserved {
+			for _, obs := range o {
+				d.AirObserved = append(d.AirObserved, observedDoc(obs))
+			}
+		}
+		if hasSensors {
+			if h, ok := air.HyperlocalAQI(sensors); ok {
+				d.Hyperlocal = hyperlocalDoc(h)
+			}
+			for _, s := range sensors {
+				d.Sensors = append(d.Sensors, sensorDoc(s, loc))
+			}
+		}
+	}
+	return d
+}
+
+// WriteJSON writes the document as indented JSON.
+func WriteJSON(out io.Writer, d Document) error {
+	e := json.NewEncoder(out)
+	e.SetIndent("", "  ")
+	return e.Encode(d)
+}
+
+func summaryDoc(w weather.Forecast, loc *time.Location) *SummaryDoc {
+	today := w.Daily.Data[0]
+	return &SummaryDoc{
+		Currently:          w.Currently.Summary,
+		ThisWeek:           w.Daily.Summary,
+		Temperature:        w.Hourly.Data[0].Temperature,
+		TemperatureMin:     today.TemperatureMin,
+		TemperatureMinTime: timestamp(today.TemperatureMinTime, loc),
+		TemperatureMax:     today.TemperatureMax,
+		TemperatureMaxTime: timestamp(today.TemperatureMaxTime, loc),
+		Humidity:           ToPercent(today.Humidity),
+		WindSpeed:          w.Currently.WindSpeed,
+		UVIndex:            w.Currently.UVIndex,
+		PrecipProbability:  ToPercent(today.PrecipProbability),
+		PrecipType:         today.PrecipType,
+		Sunrise:            timestamp(today.SunriseTime, loc),
+		Sunset:             timestamp(today.SunsetTime, loc),
+	}
+}
+
+func hourDoc(h weather.DataPoint, loc *time.Location) HourDoc {
+	return HourDoc{
+		Time:                timestamp(h.Time, loc),
+		Summary:             h.Summary,
+		Temperature:         h.Temperature,
+		ApparentTemperature: h.ApparentTemperature,
+		PrecipProbability:   ToPercent(h.PrecipProbability),
+		PrecipIntensity:     h.PrecipIntensity,
+		PrecipType:          h.PrecipType,
+		Humidity:            ToPercent(h.Humidity),
+		WindSpeed:           h.WindSpeed,
+		WindBearing:         h.WindBearing,
+		Smoke:               reported(h.Smoke),
+		FireIndex:           reported(h.FireIndex),
+	}
+}
+
+func dayDoc(day weather.DataPoint, loc *time.Location) DayDoc {
+	return DayDoc{
+		Time:               timestamp(day.Time, loc),
+		Summary:            day.Summary,
+		TemperatureMin:     day.TemperatureMin,
+		TemperatureMinTime: timestamp(day.TemperatureMinTime, loc),
+		TemperatureMax:     day.TemperatureMax,
+		TemperatureMaxTime: timestamp(day.TemperatureMaxTime, loc),
+		PrecipProbability:  ToPercent(day.PrecipProbability),
+		PrecipType:         day.PrecipType,
+		Humidity:           ToPercent(day.Humidity),
+		WindSpeed:          day.WindSpeed,
+		UVIndex:            day.UVIndex,
+		Sunrise:            timestamp(day.SunriseTime, loc),
+		Sunset:             timestamp(day.SunsetTime, loc),
+	}
+}
+
+func alertDoc(a weather.Alert, loc *time.Location) AlertDoc {
+	return AlertDoc{
+		Title:       a.Title,
+		Severity:    a.Severity,
+		Urgency:     a.Urgency,
+		Certainty:   a.Certainty,
+		Regions:     a.Regions,
+		Issued:      timestamp(a.Time, loc),
+		Expires:     timestamp(a.Expires, loc),
+		Description: a.Description,
+		URI:         a.URI,
+		New:         isNew(a),
+	}
+}
+
+func airDoc(f air.Forecast) AirDoc {
+	return AirDoc{
+		Date:           f.DateForecast,
+		Parameter:      f.ParameterName,
+		AQI:            f.AQI,
+		Category:       f.Category.Name,
+		CategoryNumber: f.Category.Number,
+		ReportingArea:  f.ReportingArea,
+		ActionDay:      f.ActionDay,
+	}
+}
+
+func observedDoc(obs air.Observation) ObservedDoc {
+	return ObservedDoc{
+		Date:           strings.TrimSpace(obs.DateObserved),
+		Hour:           obs.HourObserved,
+		TimeZone:       obs.LocalTimeZone,
+		Parameter:      obs.ParameterName,
+		AQI:            obs.AQI,
+		Category:       obs.Category.Name,
+		CategoryNumber: obs.Category.Number,
+		ReportingArea:  obs.ReportingArea,
+	}
+}
+
+func hyperlocalDoc(h air.Hyperlocal) *HyperlocalDoc {
+	return &HyperlocalDoc{
+		PM25:           h.PM25,
+		AQI:            h.AQI,
+		Category:       h.Category.Name,
+		CategoryNumber: h.Category.Number,
+		Sensors:        h.Sensors,
+		Confidence:     h.Confidence,
+	}
+}
+
+func sensorDoc(s air.Sensor, loc *time.Location) SensorDoc {
+	return SensorDoc{
+		Index:          s.Index,
+		Name:           s.Name,
+		Latitude:       s.Latitude,
+		Longitude:      s.Longitude,
+		LastSeen:       timestamp(s.LastSeen, loc),
+		PM25:           s.PM25,
+		A:              s.A,
+		B:              s.B,
+		Humidity:       s.Humidity,
+		AQI:            s.AQI,
+		Category:       s.Category.Name,
+		CategoryNumber: s.Category.Number,
+		Confidence:     s.Confidence,
+	}
+}
+
+// timestamp formats a Unix time as RFC 3339. Missing times are left empty.
+func timestamp(t float64, loc *time.Location) string {
+	if t == 0 {
+		return ""
+	}
+	return time.Unix(int64(t), 0).In(loc).Format(time.RFC3339)
+}
+
+// reported drops the -999 Pirate Weather uses for missing values.
+func reported(v *float64) *float64 {
+	if v == nil || *v < 0 {
+		return nil
+	}
+	return v
+}
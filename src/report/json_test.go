@@ -0,0 +1,107 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/jeff-bruemmer/vaporwair/src/air"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"testing"
+	"time"
+)
+
+var exForecast = weather.Forecast{
+	Timezone:  "America/Los_Angeles",
+	Offset:    -7,
+	Currently: weather.DataPoint{Summary: "Clear", WindSpeed: 4},
+	Hourly: weather.DataBlock{
+		Summary: "Clear throughout the day",
+		Data: []weather.DataPoint{
+			{Time: 1592769600, Temperature: 73.4, PrecipProbability: 0.1},
+			{Time: 1592773200, Temperature: 75},
+		},
+	},
+	Daily: weather.DataBlock{
+		Summary: "No precipitation throughout the week",
+		Data: []weather.DataPoint{
+			{Time: 1592722800, TemperatureMin: 62, TemperatureMax: 75, Humidity: 0.61,
+				SunriseTime: 1592743341, SunsetTime: 1592795310},
+		},
+	},
+}
+
+var exAir = []air.Forecast{
+	{DateForecast: "2020-06-21", ParameterName: "O3", AQI: 61, Category: air.Category{Number: 2, Name: "Moderate"}},
+	{DateForecast: "2020-06-21", ParameterName: "PM2.5", AQI: 42, Category: air.Category{Number: 1, Name: "Good"}},
+	{DateForecast: "2020-06-22", ParameterName: "O3", AQI: 80, Category: air.Category{Number: 2, Name: "Moderate"}},
+}
+
+var exObserved = []air.Observation{
+	{DateObserved: "2020-06-21 ", HourObserved: 12, LocalTimeZone: "PST", ParameterName: "O3", AQI: 48, Category: air.Category{Number: 1, Name: "Good"}},
+	{DateObserved: "2020-06-21 ", HourObserved: 12, LocalTimeZone: "PST", ParameterName: "PM2.5", AQI: 55, Category: air.Category{Number: 2, Name: "Moderate"}},
+}
+
+var exSensors = []air.Sensor{
+	{Index: 1, Name: "Backyard", PM25: 8.6, AQI: 48, Confidence: air.HighConfidence},
+	{Index: 2, Name: "Garage", PM25: 20, AQI: 68, Confidence: air.LowConfidence},
+}
+
+func TestJSONSummary(t *testing.T) {
+	now := time.Unix(1592770000, 0)
+	sources := []Source{
+		NewSource("nws", WeatherSource, now.Add(-2*time.Minute), ""),
+		NewSource("airnow", AirSource, time.Time{}, ""),
+		NewSource("airnow-observed", ObservationSource, time.Time{}, ""),
+		NewSource("purpleair", SensorSource, time.Time{}, ""),
+	}
+	d := JSON(SummaryReport, exForecast, exAir, exObserved, exSensors, Location{Latitude: 34.0308, Longitude: -118.473}, sources, now)
+	if d.Version != JSONVersion || d.Report != SummaryReport || d.Location.Timezone != "America/Los_Angeles" {
+		t.Errorf("document header = %d, %s, %s", d.Version, d.Report, d.Location.Timezone)
+	}
+	if d.Generated != "2020-06-21T13:06:40-07:00" {
+		t.Errorf("Generated = %s; want RFC 3339 in the forecast's time zone", d.Generated)
+	}
+	if d.Sources[0].CacheAge != 120 || d.Sources[1].CachedAt != "" {
+		t.Errorf("Sources = %+v; want the weather cached 120 seconds ago", d.Sources)
+	}
+	if d.Summary == nil || d.Summary.Temperature != 73.4 || d.Summary.Humidity != 61 ||
+		d.Summary.Sunrise != "2020-06-21T05:42:21-07:00" {
+		t.Errorf("Summary = %+v", d.Summary)
+	}
+	if d.AirQuality == nil || d.AirQuality.Parameter != "O3" || d.AirQuality.AQI != 61 {
+		t.Errorf("AirQuality = %+v; want today's O3 at 61", d.AirQuality)
+	}
+	if o := d.ObservedAirQuality; o == nil || o.Parameter != "PM2.5" || o.Date != "2020-06-21" || o.Hour != 12 {
+		t.Errorf("ObservedAirQuality = %+v; want PM2.5 observed at 12:00", o)
+	}
+	if h := d.Hyperlocal; h == nil || h.AQI != 48 || h.Sensors != 1 || h.Confidence != air.HighConfidence {
+		t.Errorf("Hyperlocal = %+v; want 48 from the sensor whose channels agree", h)
+	}
+	if d.Hourly != nil || d.Daily != nil || d.Air != nil || d.Sensors != nil {
+		t.Errorf("Summary document includes sections of other reports")
+	}
+}
+
+func TestJSONUnavailable(t *testing.T) {
+	sources := []Source{
+		NewSource("nws", WeatherSource, time.Time{}, "The request timed out."),
+		NewSource("airnow", AirSource, time.Time{}, ""),
+	}
+	d := JSON(HourlyReport, weather.Forecast{}, exAir, nil, nil, Location{}, sources, time.Now())
+	if d.Hourly != nil {
+		t.Errorf("Hourly = %+v; want no section for a missing forecast", d.Hourly)
+	}
+	var b bytes.Buffer
+	if err := WriteJSON(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteJSON wrote invalid JSON: %v", err)
+	}
+	if _, ok := decoded["hourly"]; ok {
+		t.Errorf("document includes an hourly section")
+	}
+	if decoded["sources"].([]interface{})[0].(map[string]interface{})["error"] != "The request timed out." {
+		t.Errorf("sources = %v; want the weather error", decoded["sources"])
+	}
+}
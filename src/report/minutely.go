@@ -0,0 +1,165 @@
+package report
+
+import (
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/air"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"math"
+	"strings"
+)
+
+// MinutelyMinutes is how far ahead the minutely report looks.
+const MinutelyMinutes = 60
+
+// A minute is wet when precipitation of at least LightIntensity, in mm/h,
+// is more likely than not. Bars are scaled to HeavyIntensity, unless the
+// forecast is heavier.
+const (
+	LightIntensity = 0.1
+	HeavyIntensity = 7.6
+)
+
+// Minutely prints the intensity and chance of precipitation minute by minute
+// over the next hour, fitted to width columns, and when it starts or stops.
+func Minutely(w weather.Forecast, a []air.Forecast, width int) {
+	fmt.Println(Paint(TitleStyle, Title("Next Hour")))
+	minutes := LimitData(w.Minutely.Data, MinutelyMinutes)
+	if len(minutes) == 0 {
+		fmt.Println("The weather provider does not forecast minute by minute here.")
+		return
+	}
+	if w.Minutely.Summary != "" {
+		fmt.Println(AddPeriod(w.Minutely.Summary))
+	}
+	fmt.Println(Paint(PrecipStyle, Nowcast(minutes)))
+	fmt.Println()
+	for _, line := range minutely(minutes, width) {
+		fmt.Println(line)
+	}
+}
+
+// wet reports whether precipitation is likely in a minute.
+func wet(d weather.DataPoint) bool {
+	mmph, _ := weather.Convert(d.PrecipIntensity, iu, "mm/h")
+	return mmph >= LightIntensity && d.PrecipProbability >= 0.5
+}
+
+// Nowcast describes when precipitation starts or stops within the minutes,
+// such as "Rain starting in 12 min, stopping 35 min later."
+func Nowcast(minutes []weather.DataPoint) string {
+	if len(minutes) == 0 {
+		return ""
+	}
+	// change returns the first minute after i that is wet when i is dry,
+	// or dry when i is wet, or -1.
+	change := func(i int) int {
+		for j := i + 1; j < len(minutes); j++ {
+			if wet(minutes[j]) != wet(minutes[i]) {
+				return j
+			}
+		}
+		return -1
+	}
+	after := func(i, j int) float64 {
+		return math.Round((minutes[j].Time - minutes[i].Time) / 60)
+	}
+	first := change(0)
+	if !wet(minutes[0]) {
+		switch {
+		case first < 0:
+			return "No precipitation for the next hour."
+		case change(first) < 0:
+			return fmt.Sprintf("%s starting in %.0f min.", precipName(minutes[first]), after(0, first))
+		}
+		return fmt.Sprintf("%s starting in %.0f min, stopping %.0f min later.",
+			precipName(minutes[first]), after(0, first), after(first, change(first)))
+	}
+	name := precipName(minutes[0])
+	switch {
+	case first < 0:
+		return name + " for the next hour."
+	case change(first) < 0:
+		return fmt.Sprintf("%s stopping in %.0f min.", name, after(0, first))
+	}
+	return fmt.Sprintf("%s stopping in %.0f min, starting again %.0f min later.",
+		name, after(0, first), after(first, change(first)))
+}
+
+// precipName names the type of precipitation in a minute, such as Rain.
+func precipName(d weather.DataPoint) string {
+	if d.PrecipType == "" {
+		return "Precipitation"
+	}
+	return capitalize(d.PrecipType)
+}
+
+// minutely returns the lines of the minutely chart: intensity, chance,
+// and the minutes from now.
+func minutely(minutes []weather.DataPoint, width int) []string {
+	cols := width - gutter
+	if cols < 1 {
+		cols = 1
+	}
+	step := int(math.Ceil(float64(len(minutes)) / float64(cols)))
+	n := (len(minutes) + step - 1) / step
+	intensity := make([]float64, n)
+	chance := make([]float64, n)
+	wets := make([]bool, n)
+	for i, d := range minutes {
+		c := i / step
+		size := float64(len(minutes[c*step : minInt(c*step+step, len(minutes))]))
+		intensity[c] += d.PrecipIntensity / size
+		chance[c] += ToPercent(d.PrecipProbability) / size
+		wets[c] = wets[c] || wet(d)
+	}
+
+	const intensityHeight = 4
+	top, _ := weather.Convert(HeavyIntensity, "mm/h", iu)
+	for _, v := range intensity {
+		top = math.Max(top, v)
+	}
+	intensities := make([]int, n)
+	chances := make([]int, n)
+	for i := range intensity {
+		intensities[i] = int(math.Ceil(intensity[i] / top * intensityHeight * 8))
+		chances[i] = int(math.Ceil(chance[i] / 100 * 2 * 8))
+	}
+	intensityStyle := func(i int) string {
+		if wets[i] {
+			return PrecipStyle
+		}
+		return ""
+	}
+	chanceStyle := func(i int) string { return ChanceStyle(chance[i]) }
+
+	var lines []string
+	for r, row := range bars(intensities, intensityHeight) {
+		l := ""
+		switch r {
+		case 0:
+			l = fmt.Sprintf("%.2g %s", top, iu)
+		case intensityHeight - 1:
+			l = "0"
+		}
+		lines = append(lines, gutterLabel(l)+paintRow(row, intensityStyle))
+	}
+	for r, row := range bars(chances, 2) {
+		l := ""
+		if r == 0 {
+			l = "Chance"
+		}
+		lines = append(lines, gutterLabel(l)+paintRow(row, chanceStyle))
+	}
+
+	// Mark every quarter hour.
+	axis := []rune(strings.Repeat(" ", n+3))
+	for m := 0; m < len(minutes); m += 15 {
+		mark := "now"
+		if m > 0 {
+			mark = fmt.Sprint(m)
+		}
+		copy(axis[m/step:], []rune(mark))
+	}
+	lines = append(lines, gutterLabel("min")+strings.TrimRight(string(axis), " "))
+	return lines
+}
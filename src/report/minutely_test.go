@@ -0,0 +1,63 @@
+package report
+
+import (
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"testing"
+	"unicode/utf8"
+)
+
+// hour returns 60 minutes of rain at 1 mm/h, dry outside [start, stop).
+func hour(start, stop int) []weather.DataPoint {
+	var minutes []weather.DataPoint
+	for m := 0; m < 60; m++ {
+		d := weather.DataPoint{Time: float64(1600000000 + m*60)}
+		if m >= start && m < stop {
+			d.PrecipIntensity = 1
+			d.PrecipProbability = 0.8
+			d.PrecipType = "rain"
+		}
+		minutes = append(minutes, d)
+	}
+	return minutes
+}
+
+func TestNowcast(t *testing.T) {
+	defer SetUnits(weather.US)
+	SetUnits(weather.SI)
+	tests := []struct {
+		start, stop int
+		want        string
+	}{
+		{0, 0, "No precipitation for the next hour."},
+		{12, 47, "Rain starting in 12 min, stopping 35 min later."},
+		{50, 60, "Rain starting in 50 min."},
+		{0, 60, "Rain for the next hour."},
+		{0, 20, "Rain stopping in 20 min."},
+	}
+	for _, tt := range tests {
+		if got := Nowcast(hour(tt.start, tt.stop)); got != tt.want {
+			t.Errorf("Nowcast(%d-%d) = %q; want %q", tt.start, tt.stop, got, tt.want)
+		}
+	}
+
+	// Unlikely or very light precipitation is left out.
+	minutes := hour(0, 60)
+	for i := range minutes {
+		minutes[i].PrecipProbability = 0.2
+	}
+	if got := Nowcast(minutes); got != "No precipitation for the next hour." {
+		t.Errorf("unlikely rain: Nowcast = %q", got)
+	}
+}
+
+func TestMinutely(t *testing.T) {
+	defer SetUnits(weather.US)
+	SetUnits(weather.SI)
+	lines := minutely(hour(12, 47), gutter+30)
+	if n := utf8.RuneCountInString(lines[0]); n != gutter+30 {
+		t.Errorf("chart is %d columns wide; want %d", n, gutter+30)
+	}
+	if got, want := lines[len(lines)-1], "     min now    15      30     45"; got != want {
+		t.Errorf("axis = %q; want %q", got, want)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"github.com/jeff-bruemmer/vaporwair/src/air"
 	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -21,21 +23,26 @@ const (
 	flags    = 0
 )
 
-// Formats
+// Formats. Units are those of the US system until SetUnits is called.
 var tu = "°F"
-var hm = "HH:MM"
 var wu = "mph"
-var pu = "atm"
-var du = "miles"
+var pu = "inHg"
+var du = "mi"
+var su = "µg/m³"
+var iu = "in/h"
 var pc = "%"
 
 // Separator separates report summaries from tables.
 var Separator = "+++"
 
-var TW = tabwriter.NewWriter(output, minwidth, tabwidth, padding, padchar, flags)
+var TW = newTabWriter(output)
+
+func newTabWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, minwidth, tabwidth, padding, padchar, flags)
+}
 
 // Formats
-var f1 = "%s:\t%.0f %s at %v %s\n"
+var f1 = "%s:\t%s at %v %s\n"
 var f2 = "%s:\t%.0f %s\n"
 var f3 = "%s:\t%v %s\n"
 var f4 = "%s:\t%v %s %s\n"
@@ -47,17 +54,22 @@ func Title(t string) string {
 	return "-- " + strings.ToUpper(t) + " --"
 }
 
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
 // Adds space padding
 func Pad(v int) string {
-	fmt.Println("v", v)
-	s := string(v)
-	fmt.Println(s)
+	s := strconv.Itoa(v)
 	var b []string
 	for i := len(s); i < 4; i++ {
 		b = append(b, " ")
 	}
 	b = append(b, s)
-	fmt.Println(b)
 	return strings.Join(b, "")
 }
 
@@ -75,11 +87,6 @@ func ToPercent(f float64) float64 {
 	return f * 100
 }
 
-// Formats time
-func FormatTime(t float64) string {
-	return time.Unix(int64(t), 0).Format("15:04")
-}
-
 // Limit slice of data, provided the slice is at least the desired length.
 func LimitData(d []weather.DataPoint, l int) []weather.DataPoint {
 	if len(d) >= l {
@@ -89,78 +96,174 @@ func LimitData(d []weather.DataPoint, l int) []weather.DataPoint {
 	}
 }
 
+// today returns the forecast for today. Providers may return no days,
+// and lines about today are then left out.
+func today(f weather.Forecast) (weather.DataPoint, bool) {
+	if len(f.Daily.Data) == 0 {
+		return weather.DataPoint{}, false
+	}
+	return f.Daily.Data[0], true
+}
+
 // Format 1
 func MinTemp(f weather.Forecast) {
-	fmt.Fprintf(TW, f1, "Min Temperature", Round(f.Daily.Data[0].TemperatureMin), tu, FormatTime(f.Daily.Data[0].TemperatureMinTime), hm)
+	d, ok := today(f)
+	if !ok {
+		return
+	}
+	fmt.Fprintf(TW, f1, "Min Temperature", Temperature(d.TemperatureMin), FormatTime(d.TemperatureMinTime), Zone(d.TemperatureMinTime))
 }
 
 // Prints maximum daily temperature and time.
 func MaxTemp(f weather.Forecast) {
-	fmt.Fprintf(TW, f1, "Max Temperature", f.Daily.Data[0].TemperatureMax, tu, FormatTime(f.Daily.Data[0].TemperatureMaxTime), hm)
+	d, ok := today(f)
+	if !ok {
+		return
+	}
+	fmt.Fprintf(TW, f1, "Max Temperature", Temperature(d.TemperatureMax), FormatTime(d.TemperatureMaxTime), Zone(d.TemperatureMaxTime))
 
 }
 
 // Format 2
 // Prints minimum daily temperature and time.
 func CurrentTemp(f weather.Forecast) {
-	fmt.Fprintf(TW, f2, "Current Temperature", Round(f.Hourly.Data[0].Temperature), tu)
+	if len(f.Hourly.Data) == 0 {
+		return
+	}
+	fmt.Fprintf(TW, f5, "Current Temperature", Temperature(f.Hourly.Data[0].Temperature))
 }
 
 // Prints humidity converted to percent.
 func Humidity(f weather.Forecast) {
-	fmt.Fprintf(TW, f2, "Humidity", ToPercent(f.Daily.Data[0].Humidity), pc)
+	d, ok := today(f)
+	if !ok {
+		return
+	}
+	fmt.Fprintf(TW, f2, "Humidity", ToPercent(d.Humidity), pc)
 }
 
 // Prints the windspeed average for the day.
 func Windspeed(f weather.Forecast) {
-	fmt.Fprintf(TW, f2, "Windspeed", f.Currently.WindSpeed, "mph")
+	fmt.Fprintf(TW, f2, "Windspeed", f.Currently.WindSpeed, wu)
 }
 
-// Prints the average cloudcover as a percentage.
+// Prints the current cloudcover as a percentage.
 func Cloudcover(f weather.Forecast) {
-	fmt.Fprintf(TW, f2, "Cloudcover", ToPercent(f.Daily.Data[0].CloudCover), pc)
+	fmt.Fprintf(TW, f2, "Cloudcover", ToPercent(f.Currently.CloudCover), pc)
 }
 
 // Prints precipitation and type of precipitation.
 func Precipitation(f weather.Forecast) {
-	fmt.Fprintf(TW, f2, "Precipitation", Round(ToPercent(f.Daily.Data[0].PrecipProbability)), pc)
-	if ToPercent(f.Daily.Data[0].PrecipProbability) > 0 {
-		fmt.Fprintf(TW, f3, "Precip Type", f.Daily.Data[0].PrecipType, "")
+	d, ok := today(f)
+	if !ok {
+		return
+	}
+	chance := Round(ToPercent(d.PrecipProbability))
+	fmt.Fprintf(TW, f5, "Precipitation", Paint(ChanceStyle(chance), fmt.Sprintf("%.0f %s", chance, pc)))
+	if ToPercent(d.PrecipProbability) > 0 {
+		fmt.Fprintf(TW, f3, "Precip Type", d.PrecipType, "")
 	}
 }
 
-// Prints the pressure in atmospheres.
+// Prints the current pressure, to hundredths of an inch of mercury.
 func Pressure(f weather.Forecast) {
-	fmt.Fprintf(TW, f2, "Pressure", f.Daily.Data[0].Pressure, pu)
+	format := f2
+	if pu == "inHg" {
+		format = "%s:\t%.2f %s\n"
+	}
+	fmt.Fprintf(TW, format, "Pressure", f.Currently.Pressure, pu)
 }
 
+// Prints the current dewpoint.
 func Dewpoint(f weather.Forecast) {
-	fmt.Fprintf(TW, f2, "Dewpoint", f.Daily.Data[0].DewPoint, tu)
+	fmt.Fprintf(TW, f2, "Dewpoint", f.Currently.DewPoint, tu)
 }
 
+// Prints the current visibility.
 func Visibility(f weather.Forecast) {
-	fmt.Fprintf(TW, f2, "Visibility", f.Daily.Data[0].Visibility, du)
+	fmt.Fprintf(TW, f2, "Visibility", f.Currently.Visibility, du)
 }
 
 // Format 3
 // Sunrise prints the time the sun rises.
 func Sunrise(f weather.Forecast) {
-	fmt.Fprintf(TW, f3, "Sunrise", FormatTime(f.Daily.Data[0].SunriseTime), hm)
+	if d, ok := today(f); ok {
+		fmt.Fprintf(TW, f3, "Sunrise", FormatTime(d.SunriseTime), Zone(d.SunriseTime))
+	}
 }
 
 // Sunset prints the time the sun sets.
 func Sunset(f weather.Forecast) {
-	fmt.Fprintf(TW, f3, "Sunset", FormatTime(f.Daily.Data[0].SunsetTime), hm)
+	if d, ok := today(f); ok {
+		fmt.Fprintf(TW, f3, "Sunset", FormatTime(d.SunsetTime), Zone(d.SunsetTime))
+	}
 }
 
 // Format 4
-// AirQualityIndex takes a forecast and lists the highest AQI index
-// and its particle type and category.
-func AirQualityIndex(f []air.Forecast) {
+// AirQualityIndex lists the highest AQI observed now beside the highest
+// forecast for today, with their particle types and categories, and the
+// PM2.5 AQI of nearby sensors.
+func AirQualityIndex(f []air.Forecast, o []air.Observation, sensors []air.Sensor) {
+	var aqis []string
+	if now, ok := topObserved(o); ok {
+		aqi := fmt.Sprintf("%v %s %s", now.AQI, now.ParameterName, now.Category.Name)
+		aqis = append(aqis, Paint(AQIStyle(now.Category.Number), aqi)+" now")
+	}
+	// AirNow returns no forecasts outside its reporting areas.
+	if top, ok := topAQI(f); ok {
+		aqi := fmt.Sprintf("%v %s %s", top.AQI, top.ParameterName, top.Category.Name)
+		aqis = append(aqis, Paint(AQIStyle(top.Category.Number), aqi)+" forecast")
+	}
+	if h, ok := air.HyperlocalAQI(sensors); ok {
+		aqi := fmt.Sprintf("%v PM2.5 %s", h.AQI, h.Category.Name)
+		aqis = append(aqis, Paint(AQIStyle(h.Category.Number), aqi)+" nearby ("+sensorCount(h)+")")
+	}
+	if len(aqis) == 0 {
+		fmt.Fprintf(TW, f5, "Air Quality Index", "No forecast for this location")
+		return
+	}
+	fmt.Fprintf(TW, f5, "Air Quality Index", strings.Join(aqis, ", "))
+}
+
+// sensorCount describes the sensors a hyperlocal AQI is based on,
+// such as 3 sensors, high confidence.
+func sensorCount(h air.Hyperlocal) string {
+	s := fmt.Sprintf("%d sensors", h.Sensors)
+	if h.Sensors == 1 {
+		s = "1 sensor"
+	}
+	return s + ", " + h.Confidence + " confidence"
+}
+
+// topObserved returns the pollutant with the highest AQI now.
+func topObserved(o []air.Observation) (air.Observation, bool) {
+	var top air.Observation
+	for _, obs := range o {
+		if obs.AQI > top.AQI {
+			top = obs
+		}
+	}
+	return top, len(o) > 0
+}
+
+// observedAt describes when and where air quality was observed,
+// such as 13:00 PST in Portland.
+func observedAt(obs air.Observation) string {
+	hour := time.Date(2000, 1, 1, obs.HourObserved, 0, 0, 0, time.UTC).Format(clock)
+	s := strings.TrimSpace(hour + " " + obs.LocalTimeZone)
+	if obs.ReportingArea != "" {
+		s += " in " + obs.ReportingArea
+	}
+	return s
+}
+
+// topAQI returns the pollutant with the highest AQI today.
+func topAQI(f []air.Forecast) (air.Forecast, bool) {
+	var top air.Forecast
+	if len(f) == 0 {
+		return top, false
+	}
 	today := f[0].DateForecast
-	var aqi int
-	var particle string
-	var category string
 	for _, measurement := range f {
 		// We are only interested in the highest AQI for today.
 		if measurement.DateForecast != today {
@@ -169,13 +272,11 @@ func AirQualityIndex(f []air.Forecast) {
 
 		// If that measurement exceeds that of the other reigning particle,
 		// a new pollutant is crowned.
-		if measurement.AQI > aqi {
-			aqi = measurement.AQI
-			particle = measurement.ParameterName
-			category = measurement.Category.Name
+		if measurement.AQI > top.AQI {
+			top = measurement
 		}
 	}
-	fmt.Fprintf(TW, f4, "Air Quality Index", aqi, particle, category)
+	return top, true
 }
 
 // Format 5
@@ -189,6 +290,11 @@ func WeeklySummary(f weather.Forecast) {
 	fmt.Fprintf(TW, f5, "This week", AddPeriod(f.Daily.Summary))
 }
 
+// Prints why a forecast could not be retrieved.
+func Unavailable(name, reason string) {
+	fmt.Fprintf(TW, f5, name+" unavailable", reason)
+}
+
 // Format 6
 // Prints the UV index
 func UVIndex(f weather.Forecast) {
@@ -5,18 +5,41 @@ import (
 	"github.com/jeff-bruemmer/vaporwair/src/weather"
 )
 
-// The default report.
-func Summary(w weather.Forecast, a []air.Forecast) {
-	WeeklySummary(w)
-	DailySummary(w)
-	CurrentTemp(w)
-	MinTemp(w)
-	MaxTemp(w)
-	Humidity(w)
-	Windspeed(w)
-	AirQualityIndex(a)
-	UVIndex(w)
-	Precipitation(w)
-	Sunrise(w)
-	Sunset(w)
+// Status explains why a forecast could not be retrieved.
+// An empty reason means the forecast arrived.
+type Status struct {
+	Weather  string
+	Air      string
+	Observed string
+	Sensors  string
+}
+
+// The default report. Lines for a forecast that did not arrive
+// are replaced by the reason it is unavailable; air quality observed now
+// and by nearby sensors is shown even without a forecast.
+func Summary(w weather.Forecast, a []air.Forecast, o []air.Observation, sensors []air.Sensor, s Status) {
+	if s.Weather != "" {
+		Unavailable("Weather", s.Weather)
+	} else {
+		AlertBanners(w)
+		WeeklySummary(w)
+		DailySummary(w)
+		CurrentTemp(w)
+		MinTemp(w)
+		MaxTemp(w)
+		Humidity(w)
+		Windspeed(w)
+	}
+	if s.Air != "" {
+		Unavailable("Air quality", s.Air)
+	}
+	if s.Air == "" || len(o) > 0 || len(sensors) > 0 {
+		AirQualityIndex(a, o, sensors)
+	}
+	if s.Weather == "" {
+		UVIndex(w)
+		Precipitation(w)
+		Sunrise(w)
+		Sunset(w)
+	}
 }
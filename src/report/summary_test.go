@@ -0,0 +1,93 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// nwsForecast returns the forecast mapped from the recorded NWS responses
+// the weather package tests with.
+func nwsForecast(t *testing.T) weather.Forecast {
+	recordings := map[string]string{
+		"/points/34.0308,-118.4730":              "points.json",
+		"/gridpoints/LOX/146,44/forecast":        "forecast.json",
+		"/gridpoints/LOX/146,44/forecast/hourly": "forecast-hourly.json",
+		"/gridpoints/LOX/146,44/stations":        "stations.json",
+		"/stations/KSMO/observations/latest":     "observation.json",
+	}
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := recordings[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		b, err := ioutil.ReadFile("../weather/testdata/nws/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(strings.Replace(string(b), weather.NWSAddress, ts.URL, -1)))
+	}))
+	defer ts.Close()
+	c := geolocation.Coordinates{Latitude: 34.0308, Longitude: -118.473}
+	wf, err := weather.NWS{Address: ts.URL}.GetForecast(context.Background(), c)
+	if err != nil {
+		t.Fatalf("GetForecast returned error: %v", err)
+	}
+	return weather.InUnits(wf, weather.US)
+}
+
+// capture returns what fn writes to the report.
+func capture(fn func()) string {
+	var b bytes.Buffer
+	saved := TW
+	defer func() { TW = saved }()
+	TW = newTabWriter(&b)
+	fn()
+	TW.Flush()
+	return b.String()
+}
+
+func TestSummaryNWS(t *testing.T) {
+	wf := nwsForecast(t)
+	got := capture(func() {
+		Summary(wf, nil, nil, nil, Status{Air: "no API key"})
+		Pressure(wf)
+		Dewpoint(wf)
+		Visibility(wf)
+		Cloudcover(wf)
+	})
+	// Compare words, not the padding of the columns.
+	got = strings.Join(strings.Fields(got), " ")
+	for _, want := range []string{
+		"Current Temperature: 73 °F",
+		"Min Temperature: 62 °F",
+		"Max Temperature: 75 °F",
+		"Windspeed: 10 mph",
+		"Pressure: 29.97 inHg",
+		"Dewpoint: 59 °F",
+		"Visibility: 10 mi",
+		"Cloudcover: 0 %",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary does not contain %q: %s", want, got)
+		}
+	}
+}
+
+func TestSummaryEmptyBlocks(t *testing.T) {
+	wf := weather.Forecast{Currently: weather.DataPoint{Summary: "Clear", WindSpeed: 4}}
+	got := capture(func() {
+		Summary(wf, nil, nil, nil, Status{Air: "no API key"})
+	})
+	if !strings.Contains(got, "Windspeed:") || strings.Contains(got, "Min Temperature") {
+		t.Errorf("summary without daily or hourly data:\n%s", got)
+	}
+}
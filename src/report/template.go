@@ -0,0 +1,116 @@
+package report
+
+import (
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/air"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"io"
+	"text/template"
+	"time"
+)
+
+// TemplateData is what report templates are rendered with.
+//
+// Weather and Air are the forecasts, Observed is the air quality observed
+// now, and Sensors the readings of nearby sensors; see weather.Forecast,
+// air.Forecast, air.Observation and air.Sensor for their fields. Weather
+// values are in the units named by Units, and probabilities, humidity and
+// cloud cover are fractions from 0 to 1. A forecast that did not arrive is
+// empty, and Status explains why. Report names the report selected by
+// flags, such as "hourly", so one template can serve several reports.
+type TemplateData struct {
+	Report   string
+	Weather  weather.Forecast
+	Air      []air.Forecast
+	Observed []air.Observation
+	Sensors  []air.Sensor
+	Location Location
+	Units    Units
+	Status   Status
+	Now      time.Time
+}
+
+// NewTemplateData gathers the forecasts for a template.
+func NewTemplateData(report string, w weather.Forecast, a []air.Forecast, o []air.Observation, sensors []air.Sensor, l Location, s Status, now time.Time) TemplateData {
+	l.Timezone = w.Timezone
+	return TemplateData{
+		Report:   report,
+		Weather:  w,
+		Air:      a,
+		Observed: o,
+		Sensors:  sensors,
+		Location: l,
+		Units:    units(),
+		Status:   s,
+		Now:      now,
+	}
+}
+
+// NewTemplate parses a report template. Besides the text/template builtins,
+// templates can call:
+//
+//	convert V FROM TO  converts V between units, e.g. convert .Temperature "°F" "°C"
+//	percent F          converts a fraction to a rounded percentage
+//	round V            rounds V to a whole number
+//	time T LAYOUT      formats a Unix time with a Go time layout
+//	clock T            formats a Unix time on a 12-hour or 24-hour clock
+//	day T              formats a Unix time as the day of the week, e.g. Mon
+//	nowcast DATA       says when precipitation starts or stops in minutely data
+//	topAQI AIR         returns today's air.Forecast with the highest AQI
+//	topObserved OBS    returns the air.Observation with the highest AQI
+//	hyperlocal SENSORS returns the air.Hyperlocal AQI of the sensors
+//	aqiCategory AQI    names the category of an AQI, e.g. Moderate
+//	pad V WIDTH        pads V with spaces on the right to WIDTH
+//	lpad V WIDTH       pads V with spaces on the left to WIDTH
+//	period S           ends S with a period
+//	title S            frames S as a report title
+//	limit DATA N       returns the first N data points
+//
+// Times are in the report's time zone.
+func NewTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(funcs(time.Local)).Parse(text)
+}
+
+// Render executes a report template.
+func Render(out io.Writer, t *template.Template, d TemplateData) error {
+	return t.Funcs(funcs(zone(d.Weather))).Execute(out, d)
+}
+
+// funcs returns the helpers for templates, with times in loc.
+func funcs(loc *time.Location) template.FuncMap {
+	in := func(t float64) time.Time {
+		return time.Unix(int64(t), 0).In(loc)
+	}
+	return template.FuncMap{
+		"convert": weather.Convert,
+		"percent": func(f float64) float64 { return Round(ToPercent(f)) },
+		"round":   func(v float64) float64 { return Round(v) },
+		"time": func(t float64, layout string) string {
+			if t == 0 {
+				return ""
+			}
+			return in(t).Format(layout)
+		},
+		"clock":   func(t float64) string { return in(t).Format(clock) },
+		"day":     func(t float64) string { return in(t).Format("Mon") },
+		"nowcast": Nowcast,
+		"topAQI": func(a []air.Forecast) air.Forecast {
+			top, _ := topAQI(a)
+			return top
+		},
+		"topObserved": func(o []air.Observation) air.Observation {
+			top, _ := topObserved(o)
+			return top
+		},
+		"hyperlocal": func(s []air.Sensor) air.Hyperlocal {
+			h, _ := air.HyperlocalAQI(s)
+			return h
+		},
+		"aqiCategory": func(aqi int) string { return air.CategoryOf(aqi).Name },
+		"pad":         func(v interface{}, n int) string { return fmt.Sprintf("%-*v", n, v) },
+		"lpad":        func(v interface{}, n int) string { return fmt.Sprintf("%*v", n, v) },
+		"period":      AddPeriod,
+		"title":       Title,
+		"limit":       LimitData,
+	}
+}
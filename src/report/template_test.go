@@ -0,0 +1,28 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	text := `{{with .Weather.Hourly.Data}}{{range limit . 1}}{{clock .Time}} {{day .Time}} ` +
+		`{{lpad (round .Temperature) 3}}{{$.Units.Temperature}} {{printf "%.1f" (convert .Temperature "°F" "°C")}}°C ` +
+		`{{percent .PrecipProbability}}%{{end}}{{end}}
+{{with topAQI .Air}}{{pad .ParameterName 6}}|{{.AQI}} {{aqiCategory .AQI}}{{end}}
+{{if .Status.Air}}{{.Status.Air}}{{else}}{{.Report}} {{.Location.Timezone}}{{end}}`
+	tmpl, err := NewTemplate("test", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	d := NewTemplateData(HourlyReport, exForecast, exAir, nil, nil, Location{}, Status{}, time.Unix(1592770000, 0))
+	if err := Render(&b, tmpl, d); err != nil {
+		t.Fatal(err)
+	}
+	want := "13:00 Sun  73°F 23.0°C 10%\nO3    |61 Moderate\nhourly America/Los_Angeles"
+	if b.String() != want {
+		t.Errorf("Render =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -4,24 +4,63 @@ import (
 	"fmt"
 	"github.com/jeff-bruemmer/vaporwair/src/air"
 	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"strings"
 )
 
 func WeatherHourly(w weather.Forecast, a []air.Forecast) {
-	fmt.Println(Title("Hourly Summary"))
+	fmt.Println(Paint(TitleStyle, Title("Hourly Summary")))
 	fmt.Println(AddPeriod(w.Hourly.Summary))
 	fmt.Println()
-	format := "%v\t%.0f %s\t%.0f %s\t%.0f %s\t%.2f %s\t%.0f %s\n"
-	fmt.Fprintf(TW, "Hour\tTemp\tFeels Like\tPrecip\tIntensity\tWind\n")
-	fmt.Fprintf(TW, "----\t----\t----------\t------\t---------\t----\n")
+	format := "%v\t%s\t%s\t%s\t%.2f %s\t%.0f %s"
 	d := LimitData(w.Hourly.Data, 12)
+	// Pirate Weather also reports smoke and fire danger.
+	fire := hasFireData(d)
+	// Label the hours with the time zone they are shown in.
+	hour := "Hour"
+	if len(d) > 0 {
+		hour += " (" + Zone(d[0].Time) + ")"
+	}
+	rule := strings.Repeat("-", len(hour))
+	// Colored columns are painted in every row to stay aligned.
+	heading := hour + "\t" + PaintCell("", "Temp") + "\t" + PaintCell("", "Feels Like") + "\t" + PaintCell("", "Precip") + "\tIntensity\tWind"
+	rule += "\t" + PaintCell("", "----") + "\t" + PaintCell("", "----------") + "\t" + PaintCell("", "------") + "\t---------\t----"
+	if fire {
+		heading += "\tSmoke\tFire Index"
+		rule += "\t-----\t----------"
+	}
+	fmt.Fprintln(TW, heading)
+	fmt.Fprintln(TW, rule)
 	for _, h := range d {
 		fmt.Fprintf(TW, format,
 			FormatTime(h.Time),
-			h.Temperature, tu,
-			h.ApparentTemperature, tu,
-			ToPercent(h.PrecipProbability), pc,
-			ToPercent(h.PrecipIntensity), "mmph",
+			TemperatureCell(h.Temperature),
+			TemperatureCell(h.ApparentTemperature),
+			ChanceCell(ToPercent(h.PrecipProbability)),
+			h.PrecipIntensity, iu,
 			h.WindSpeed, wu)
+		if fire {
+			fmt.Fprintf(TW, "\t%s\t%s", optional(h.Smoke, su), optional(h.FireIndex, ""))
+		}
+		fmt.Fprintln(TW)
 	}
 	TW.Flush()
 }
+
+// hasFireData reports whether any hour includes smoke or fire index values.
+func hasFireData(d []weather.DataPoint) bool {
+	for _, h := range d {
+		if h.Smoke != nil || h.FireIndex != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// optional formats a value the provider may not report.
+// Pirate Weather uses -999 for missing values.
+func optional(v *float64, unit string) string {
+	if v == nil || *v < 0 {
+		return "-"
+	}
+	return strings.TrimSpace(fmt.Sprintf("%.0f %s", *v, unit))
+}
@@ -4,24 +4,24 @@ import (
 	"fmt"
 	"github.com/jeff-bruemmer/vaporwair/src/air"
 	"github.com/jeff-bruemmer/vaporwair/src/weather"
-	"time"
 )
 
 func WeatherWeek(w weather.Forecast, a []air.Forecast) {
-	fmt.Println(Title("Weekly Summary"))
+	fmt.Println(Paint(TitleStyle, Title("Weekly Summary")))
 	fmt.Println(AddPeriod(w.Daily.Summary))
 	fmt.Println(Separator)
 	data := LimitData(w.Daily.Data, 7)
 	formatTitle := "%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
-	formatBody := "%v\t%.0f %s\t%.0f %s\t%.0f %s\t%s\t%.0f %s\t%.0f %s\n"
-	fmt.Fprintf(TW, formatTitle, "Day", "Min", "Max", "Precip", "Type", "Humidity", "Wind")
-	fmt.Fprintf(TW, formatTitle, "---", "---", "---", "------", "----", "--------", "----")
+	formatBody := "%v\t%s\t%s\t%s\t%s\t%.0f %s\t%.0f %s\n"
+	// Colored columns are painted in every row to stay aligned.
+	fmt.Fprintf(TW, formatTitle, "Day", PaintCell("", "Min"), PaintCell("", "Max"), PaintCell("", "Precip"), "Type", "Humidity", "Wind")
+	fmt.Fprintf(TW, formatTitle, "---", PaintCell("", "---"), PaintCell("", "---"), PaintCell("", "------"), "----", "--------", "----")
 	for _, day := range data {
 		fmt.Fprintf(TW, formatBody,
-			time.Unix(int64(day.Time), 0).Format("Mon"),
-			day.TemperatureMin, tu,
-			day.TemperatureMax, tu,
-			ToPercent(day.PrecipProbability), pc,
+			local(day.Time).Format("Mon"),
+			TemperatureCell(day.TemperatureMin),
+			TemperatureCell(day.TemperatureMax),
+			ChanceCell(ToPercent(day.PrecipProbability)),
 			day.PrecipType,
 			ToPercent(day.Humidity), pc,
 			day.WindSpeed, wu,
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"encoding/json"
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+const AlertsFileName = VaporwairDir + "alerts.json"
+
+// SeenAlertsTTL is how long an alert without an expiry is remembered
+// after it was last shown.
+const SeenAlertsTTL = 24 * time.Hour
+
+// SeenAlerts remembers the weather alerts already shown, by ID, until the
+// Unix time they expire.
+type SeenAlerts map[string]float64
+
+// LoadSeenAlerts reads the alerts already shown. A missing file holds none.
+func LoadSeenAlerts(path string) (SeenAlerts, error) {
+	s := SeenAlerts{}
+	err := loadJSON(path, &s)
+	if os.IsNotExist(err) {
+		return SeenAlerts{}, nil
+	}
+	if s == nil {
+		s = SeenAlerts{}
+	}
+	return s, err
+}
+
+// SaveSeenAlerts writes the alerts already shown to path.
+func SaveSeenAlerts(path string, s SeenAlerts) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// Update remembers the alerts shown now and forgets expired ones.
+// It returns the IDs of the alerts not seen before.
+func (s SeenAlerts) Update(alerts []weather.Alert, now time.Time) map[string]bool {
+	for id, expires := range s {
+		if expires <= float64(now.Unix()) {
+			delete(s, id)
+		}
+	}
+	fresh := map[string]bool{}
+	for _, a := range alerts {
+		if _, ok := s[a.ID()]; !ok {
+			fresh[a.ID()] = true
+		}
+		expires := a.Expires
+		if expires == 0 {
+			expires = float64(now.Add(SeenAlertsTTL).Unix())
+		}
+		s[a.ID()] = expires
+	}
+	return fresh
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"github.com/jeff-bruemmer/vaporwair/src/weather"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSeenAlerts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	now := time.Unix(1600000000, 0)
+	wind := weather.Alert{Title: "Wind Advisory", Time: 1599990000, Expires: 1600010000}
+	flood := weather.Alert{Title: "Flood Watch", Time: 1599990000}
+
+	seen, err := LoadSeenAlerts(path)
+	if err != nil || len(seen) != 0 {
+		t.Fatalf("LoadSeenAlerts on missing file = %v, %v; want none", seen, err)
+	}
+	if fresh := seen.Update([]weather.Alert{wind}, now); !fresh[wind.ID()] {
+		t.Errorf("Update = %v; want the wind advisory new", fresh)
+	}
+	if err := SaveSeenAlerts(path, seen); err != nil {
+		t.Fatal(err)
+	}
+
+	seen, err = LoadSeenAlerts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := seen.Update([]weather.Alert{wind, flood}, now.Add(time.Hour))
+	if fresh[wind.ID()] || !fresh[flood.ID()] {
+		t.Errorf("Update = %v; want only the flood watch new", fresh)
+	}
+
+	// Expired alerts are forgotten, and alerts without an expiry are
+	// remembered for a day after they were last shown.
+	seen.Update(nil, now.Add(3*time.Hour))
+	if _, ok := seen[wind.ID()]; ok {
+		t.Error("expired wind advisory is still remembered")
+	}
+	if _, ok := seen[flood.ID()]; !ok {
+		t.Error("flood watch was forgotten before a day passed")
+	}
+}
@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/air"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const CacheDir = VaporwairDir + "cache/"
+
+// Cache defaults. Air quality forecasts are issued once or twice a day,
+// so they stay fresh far longer than weather forecasts. Air quality is
+// observed every hour, and PurpleAir readings are kept as long as
+// observations.
+const (
+	DefaultWeatherTTL  = 10 * time.Minute
+	DefaultAirTTL      = 60 * time.Minute
+	DefaultObservedTTL = 15 * time.Minute
+	DefaultMaxEntries  = 64
+	DefaultMaxBytes    = 16 << 20
+)
+
+// Cache keys for current air quality observations, which are cached
+// under the provider's name and ObservedSuffix.
+const (
+	ObservedSuffix     = "-observed"
+	AirNowObservedName = air.AirNowName + ObservedSuffix
+	OpenAQObservedName = air.OpenAQName + ObservedSuffix
+)
+
+// ErrMiss is returned when the cache holds no fresh entry
+// for a provider and location.
+var ErrMiss = errors.New("no fresh forecast in cache")
+
+// CacheConfig overrides the cache defaults in the config file.
+// TTLs are in minutes, keyed by provider name.
+type CacheConfig struct {
+	TTLMinutes map[string]int `json:"ttlminutes,omitempty"`
+	MaxEntries int            `json:"maxentries,omitempty"`
+	MaxBytes   int64          `json:"maxbytes,omitempty"`
+}
+
+// Cache stores forecasts on disk, one file per provider and location.
+// Locations are rounded to two decimal places, roughly a kilometer, so
+// small differences in reported coordinates share an entry, and a fresh
+// entry for a location within MatchRadius kilometers is reused. Reading an
+// entry marks it as recently used; once the cache grows past MaxEntries
+// or MaxBytes, the least recently used entries are evicted.
+type Cache struct {
+	Dir         string
+	TTL         map[string]time.Duration
+	DefaultTTL  time.Duration
+	MaxEntries  int
+	MaxBytes    int64
+	MatchRadius float64
+}
+
+// cacheEntry is the file format of a cache entry.
+type cacheEntry struct {
+	Saved       time.Time               `json:"saved"`
+	Provider    string                  `json:"provider"`
+	Coordinates geolocation.Coordinates `json:"coordinates"`
+	Data        json.RawMessage         `json:"data"`
+}
+
+// NewCache returns a cache in dir configured by c, falling back on the defaults.
+func NewCache(dir string, c CacheConfig) Cache {
+	cache := Cache{
+		Dir: dir,
+		TTL: map[string]time.Duration{
+			air.AirNowName:     DefaultAirTTL,
+			AirNowObservedName: DefaultObservedTTL,
+			OpenAQObservedName: DefaultObservedTTL,
+			air.PurpleAirName:  DefaultObservedTTL,
+		},
+		DefaultTTL:  DefaultWeatherTTL,
+		MaxEntries:  DefaultMaxEntries,
+		MaxBytes:    DefaultMaxBytes,
+		MatchRadius: geolocation.DefaultMatchRadius,
+	}
+	for provider, minutes := range c.TTLMinutes {
+		cache.TTL[provider] = time.Duration(minutes) * time.Minute
+	}
+	if c.MaxEntries > 0 {
+		cache.MaxEntries = c.MaxEntries
+	}
+	if c.MaxBytes > 0 {
+		cache.MaxBytes = c.MaxBytes
+	}
+	return cache
+}
+
+// ttl returns how long the provider's forecasts stay fresh.
+func (c Cache) ttl(provider string) time.Duration {
+	if d, ok := c.TTL[provider]; ok {
+		return d
+	}
+	return c.DefaultTTL
+}
+
+// roundCoordinate rounds a coordinate to two decimal places.
+func roundCoordinate(c float64) string {
+	return strconv.FormatFloat(c, 'f', 2, 64)
+}
+
+// path returns the file for a provider and location.
+func (c Cache) path(provider string, coords geolocation.Coordinates) string {
+	name := provider + "_" + roundCoordinate(coords.Latitude) + "_" + roundCoordinate(coords.Longitude) + ".json"
+	return filepath.Join(c.Dir, name)
+}
+
+// Get decodes the cached forecast from provider for the location into v
+// and returns when it was saved. Without a fresh entry for the location,
+// the nearest fresh entry within MatchRadius is used. ErrMiss is returned
+// if there is none.
+func (c Cache) Get(provider string, coords geolocation.Coordinates, v interface{}) (time.Time, error) {
+	var e cacheEntry
+	path := c.path(provider, coords)
+	err := loadJSON(path, &e)
+	if err != nil && !os.IsNotExist(err) {
+		return time.Time{}, err
+	}
+	if err != nil || time.Since(e.Saved) >= c.ttl(provider) {
+		var ok bool
+		if path, e, ok = c.nearest(provider, coords); !ok {
+			return time.Time{}, ErrMiss
+		}
+	}
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return e.Saved, fmt.Errorf("%w: %s: %v", ErrCorrupt, path, err)
+	}
+	// Mark the entry as recently used.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return e.Saved, nil
+}
+
+// nearest returns the fresh entry from provider closest to the location,
+// if one is within MatchRadius kilometers.
+func (c Cache) nearest(provider string, coords geolocation.Coordinates) (string, cacheEntry, bool) {
+	var path string
+	var nearest cacheEntry
+	best := c.MatchRadius
+	found := false
+	entries, err := c.entries()
+	if err != nil {
+		return path, nearest, false
+	}
+	for _, f := range entries {
+		if !strings.HasPrefix(f.Name(), provider+"_") {
+			continue
+		}
+		var e cacheEntry
+		p := filepath.Join(c.Dir, f.Name())
+		// Skip entries that cannot be read; Get reports its own corrupt entry.
+		if err := loadJSON(p, &e); err != nil || e.Provider != provider {
+			continue
+		}
+		if time.Since(e.Saved) >= c.ttl(provider) {
+			continue
+		}
+		if d := geolocation.Distance(coords, e.Coordinates); d <= best {
+			path, nearest, best, found = p, e, d, true
+		}
+	}
+	return path, nearest, found
+}
+
+// Put saves a forecast from provider for the location, then evicts
+// the least recently used entries if the cache is too large.
+func (c Cache) Put(provider string, coords geolocation.Coordinates, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e := cacheEntry{
+		Saved:       time.Now(),
+		Provider:    provider,
+		Coordinates: coords,
+		Data:        data,
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(c.path(provider, coords), b, 0644); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// entries lists the cache files, most recently used first.
+func (c Cache) entries() ([]os.FileInfo, error) {
+	files, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []os.FileInfo
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") {
+			entries = append(entries, f)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().After(entries[j].ModTime())
+	})
+	return entries, nil
+}
+
+// evict removes the least recently used entries until the cache
+// is within its limits.
+func (c Cache) evict() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	var size int64
+	for i, f := range entries {
+		size += f.Size()
+		if i >= c.MaxEntries || size > c.MaxBytes {
+			if err := os.Remove(filepath.Join(c.Dir, f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Latest returns the location of the most recently used entry. Vaporwair
+// assumes the user is still there while it waits for the geolocation service.
+// ErrMiss is returned if the cache is empty.
+func (c Cache) Latest() (geolocation.Coordinates, error) {
+	entries, err := c.entries()
+	if err != nil && !os.IsNotExist(err) {
+		return geolocation.Coordinates{}, err
+	}
+	for _, f := range entries {
+		var e cacheEntry
+		if err := loadJSON(filepath.Join(c.Dir, f.Name()), &e); err == nil {
+			return e.Coordinates, nil
+		}
+	}
+	return geolocation.Coordinates{}, ErrMiss
+}
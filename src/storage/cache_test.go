@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"github.com/jeff-bruemmer/vaporwair/src/air"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	home  = geolocation.Coordinates{Latitude: 34.0308, Longitude: -118.473}
+	away  = geolocation.Coordinates{Latitude: 40.7128, Longitude: -74.0060}
+	third = geolocation.Coordinates{Latitude: 51.5072, Longitude: -0.1276}
+)
+
+func TestCacheGetPut(t *testing.T) {
+	c := NewCache(t.TempDir(), CacheConfig{})
+	var v string
+	if _, err := c.Get("nws", home, &v); err != ErrMiss {
+		t.Fatalf("Get on empty cache = %v; want ErrMiss", err)
+	}
+	if err := c.Put("nws", home, "sunny"); err != nil {
+		t.Fatal(err)
+	}
+	// Nearby coordinates round to the same entry.
+	near := geolocation.Coordinates{Latitude: 34.0311, Longitude: -118.4729}
+	if _, err := c.Get("nws", near, &v); err != nil || v != "sunny" {
+		t.Fatalf("Get = %q, %v; want sunny", v, err)
+	}
+	if _, err := c.Get(air.AirNowName, home, &v); err != ErrMiss {
+		t.Errorf("Get for another provider = %v; want ErrMiss", err)
+	}
+
+	c.TTL["nws"] = 0
+	if _, err := c.Get("nws", home, &v); err != ErrMiss {
+		t.Errorf("Get on expired entry = %v; want ErrMiss", err)
+	}
+}
+
+func TestCacheGetNearby(t *testing.T) {
+	c := NewCache(t.TempDir(), CacheConfig{})
+	// 100 m apart, on either side of a rounding boundary.
+	north := geolocation.Coordinates{Latitude: 34.0358, Longitude: -118.473}
+	south := geolocation.Coordinates{Latitude: 34.0349, Longitude: -118.473}
+	if c.path("nws", north) == c.path("nws", south) {
+		t.Fatal("test points share an entry")
+	}
+	if err := c.Put("nws", north, "sunny"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("nws", away, "rainy"); err != nil {
+		t.Fatal(err)
+	}
+	var v string
+	if _, err := c.Get("nws", south, &v); err != nil || v != "sunny" {
+		t.Errorf("Get 100 m away = %q, %v; want sunny", v, err)
+	}
+	if _, err := c.Get(air.AirNowName, south, &v); err != ErrMiss {
+		t.Errorf("Get for another provider = %v; want ErrMiss", err)
+	}
+
+	c.MatchRadius = 0.05
+	if _, err := c.Get("nws", south, &v); err != ErrMiss {
+		t.Errorf("Get beyond MatchRadius = %v; want ErrMiss", err)
+	}
+	c.MatchRadius = geolocation.DefaultMatchRadius
+	c.TTL["nws"] = 0
+	if _, err := c.Get("nws", south, &v); err != ErrMiss {
+		t.Errorf("Get of an expired nearby entry = %v; want ErrMiss", err)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := NewCache(t.TempDir(), CacheConfig{MaxEntries: 2})
+	old := time.Now().Add(-time.Hour)
+	for i, coords := range []geolocation.Coordinates{home, away} {
+		if err := c.Put("nws", coords, i); err != nil {
+			t.Fatal(err)
+		}
+		// Age entries so their order does not depend on timer resolution.
+		at := old.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(c.path("nws", coords), at, at)
+	}
+	// Reading home makes away the least recently used entry.
+	var v int
+	if _, err := c.Get("nws", home, &v); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("nws", third, 2); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("cache holds %d entries; want 2", len(files))
+	}
+	if _, err := c.Get("nws", away, &v); err != ErrMiss {
+		t.Errorf("least recently used entry was not evicted")
+	}
+}
+
+func TestCacheLatest(t *testing.T) {
+	c := NewCache(t.TempDir(), CacheConfig{})
+	if _, err := c.Latest(); err != ErrMiss {
+		t.Fatalf("Latest on empty cache = %v; want ErrMiss", err)
+	}
+	c.Put("nws", home, 0)
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(c.path("nws", home), old, old)
+	c.Put("nws", away, 1)
+	latest, err := c.Latest()
+	if err != nil || latest != away {
+		t.Errorf("Latest = %v, %v; want %v", latest, err, away)
+	}
+}
+
+func TestNewCacheTTL(t *testing.T) {
+	c := NewCache(t.TempDir(), CacheConfig{TTLMinutes: map[string]int{"nws": 5}})
+	for provider, want := range map[string]time.Duration{
+		"nws":              5 * time.Minute,
+		"openmeteo":        DefaultWeatherTTL,
+		air.AirNowName:     DefaultAirTTL,
+		AirNowObservedName: DefaultObservedTTL,
+		air.PurpleAirName:  DefaultObservedTTL,
+	} {
+		if got := c.ttl(provider); got != want {
+			t.Errorf("ttl(%s) = %v; want %v", provider, got, want)
+		}
+	}
+}
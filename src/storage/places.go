@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+const PlacesFileName = VaporwairDir + "places.json"
+
+// ErrUnknownPlace is returned when a named place has not been saved.
+var ErrUnknownPlace = errors.New("no saved place")
+
+// Places holds named locations saved with the locations subcommand.
+// If Default names one of them, vaporwair reports on it instead of
+// looking up the user's location by IP address.
+type Places struct {
+	Default string                             `json:"default,omitempty"`
+	Places  map[string]geolocation.Coordinates `json:"places"`
+}
+
+// LoadPlaces reads the saved places. A missing file holds no places.
+func LoadPlaces(path string) (Places, error) {
+	p := Places{Places: map[string]geolocation.Coordinates{}}
+	err := loadJSON(path, &p)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if p.Places == nil {
+		p.Places = map[string]geolocation.Coordinates{}
+	}
+	return p, err
+}
+
+// SavePlaces writes the places to path.
+func SavePlaces(path string, p Places) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// Get returns the coordinates of a named place.
+func (p Places) Get(name string) (geolocation.Coordinates, error) {
+	c, ok := p.Places[name]
+	if !ok {
+		return c, fmt.Errorf("%w: %s", ErrUnknownPlace, name)
+	}
+	return c, nil
+}
+
+// Remove deletes a named place, clearing the default if it was the default.
+func (p *Places) Remove(name string) error {
+	if _, ok := p.Places[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPlace, name)
+	}
+	delete(p.Places, name)
+	if p.Default == name {
+		p.Default = ""
+	}
+	return nil
+}
+
+// Names returns the names of the saved places in alphabetical order.
+func (p Places) Names() []string {
+	names := make([]string, 0, len(p.Places))
+	for name := range p.Places {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "places.json")
+	p, err := LoadPlaces(path)
+	if err != nil || len(p.Places) != 0 {
+		t.Fatalf("LoadPlaces on missing file = %+v, %v; want no places", p, err)
+	}
+	p.Places["home"] = home
+	p.Places["work"] = away
+	p.Default = "home"
+	if err := SavePlaces(path, p); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = LoadPlaces(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := p.Get("work"); err != nil || c != away {
+		t.Errorf("Get(work) = %v, %v; want %v", c, err, away)
+	}
+	if _, err := p.Get("gym"); !errors.Is(err, ErrUnknownPlace) {
+		t.Errorf("Get(gym) returned %v; want ErrUnknownPlace", err)
+	}
+	// Removing the default place clears the default.
+	if err := p.Remove("home"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Default != "" {
+		t.Errorf("Default = %q after removing it; want none", p.Default)
+	}
+	if names := p.Names(); len(names) != 1 || names[0] != "work" {
+		t.Errorf("Names() = %v; want [work]", names)
+	}
+}
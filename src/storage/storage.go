@@ -4,34 +4,66 @@ package storage
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/jeff-bruemmer/vaporwair/src/air"
-	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
 	"github.com/jeff-bruemmer/vaporwair/src/weather"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/user"
 	"strings"
-	"time"
 )
 
 const VaporwairDir = "/.vaporwair/"
-const SavedWeatherFileName = VaporwairDir + "weather-forecast.json"
-const SavedAirFileName = VaporwairDir + "air-forecast.json"
 const ConfigFileName = VaporwairDir + "config.json"
-const SavedCallFileName = VaporwairDir + "last-call.json"
 
-// The Config type is used to store API keys.
+// ErrCorrupt is returned when a file in the vaporwair directory
+// does not contain the expected JSON.
+var ErrCorrupt = errors.New("file is corrupt")
+
+// The Config type is used to store API keys and the weather provider.
+// An empty WeatherProvider selects the National Weather Service.
+// Any other name not built into vaporwair refers to one of the Profiles.
+// Retries overrides how many times failed requests are retried.
+// Cache overrides the forecast cache defaults.
+// MatchRadius is how far, in kilometers, the user can move before
+// forecasts for the last location are no longer used.
+// Locators lists the IP geolocation services to try, in order, or all at
+// once if RaceLocators is set. GPSD is the address of a gpsd server
+// to ask before them. Columns selects the fields exported as CSV or TSV.
+// Units is the unit system of reports: us, si, ca, uk or auto.
+// TimeZone is local, forecast or an IANA name such as Europe/Paris,
+// and Clock is 12 or 24 hours. Color is auto, always or never, and Theme
+// names a color theme in the themes directory. Alerts is the service
+// severe weather alerts come from: nws, the default, or none.
+// AirProvider is the air quality service: airnow, the default, which
+// covers the United States, or openaq, which covers the world.
+// PurpleAir selects nearby PurpleAir sensors for a hyperlocal PM2.5 AQI.
+// OfflineZip stops ZIP codes missing from the gazetteer from being looked up
+// with Zippopotam.us.
 type Config struct {
-	DarkSkyAPIKey string `json:"darkskyapikey"`
-	AirNowAPIKey  string `json:"airnowapikey"`
-}
-
-// APICallInfo contains metadata to determine validity of last API call.
-type APICallInfo struct {
-	Time        time.Time
-	Coordinates geolocation.Coordinates
+	DarkSkyAPIKey   string                     `json:"darkskyapikey"`
+	AirNowAPIKey    string                     `json:"airnowapikey"`
+	OpenAQAPIKey    string                     `json:"openaqapikey,omitempty"`
+	AirProvider     string                     `json:"airprovider,omitempty"`
+	PurpleAir       *air.PurpleAirConfig       `json:"purpleair,omitempty"`
+	WeatherProvider string                     `json:"weatherprovider"`
+	Profiles        map[string]weather.Profile `json:"profiles,omitempty"`
+	Retries         int                        `json:"retries,omitempty"`
+	Cache           CacheConfig                `json:"cache,omitempty"`
+	MatchRadius     float64                    `json:"matchradius,omitempty"`
+	Locators        []string                   `json:"locators,omitempty"`
+	RaceLocators    bool                       `json:"racelocators,omitempty"`
+	IPInfoToken     string                     `json:"ipinfotoken,omitempty"`
+	GPSD            string                     `json:"gpsd,omitempty"`
+	Columns         []string                   `json:"columns,omitempty"`
+	Units           string                     `json:"units,omitempty"`
+	TimeZone        string                     `json:"timezone,omitempty"`
+	Clock           int                        `json:"clock,omitempty"`
+	Color           string                     `json:"color,omitempty"`
+	Theme           string                     `json:"theme,omitempty"`
+	Alerts          string                     `json:"alerts,omitempty"`
+	OfflineZip      bool                       `json:"offlinezip,omitempty"`
 }
 
 // Determines home directory in order to create vaporwair
@@ -95,119 +127,30 @@ func CreateVaporwairDir(path string) {
 	}
 }
 
-// Loads previous weather forecast.
-func LoadSavedWeather(path string) (weather.Forecast, error) {
-	var f weather.Forecast
+// loadJSON reads a saved file and decodes it into v.
+// Files that cannot be decoded are reported as ErrCorrupt.
+func loadJSON(path string, v interface{}) error {
 	b, err := ioutil.ReadFile(path)
 	if err != nil {
-		fmt.Println("Error reading forecast from disk.", err)
-	}
-	err = json.Unmarshal(b, &f)
-	if err != nil {
-		log.Fatal("Error unmarshalling json into Forecast.", err)
-	}
-	return f, nil
-}
-
-// Loads previous air quality forecast.
-func LoadSavedAir(path string) ([]air.Forecast, error) {
-	var f []air.Forecast
-	b, err := ioutil.ReadFile(path)
-	if err != nil {
-		fmt.Println("Error reading forecast from disk.", err)
-	}
-	err = json.Unmarshal(b, &f)
-	if err != nil {
-		log.Fatal("Error unmarshalling json into Forecast.", err)
-	}
-	return f, nil
-}
-
-// Checks home folder for vaporwair config file to retrieve API keys.
-func GetConfig(filepath string) Config {
-	configFile, err := os.Open(filepath)
-	if err != nil {
-		fmt.Println("Could not find config file in home directory.")
-		log.Fatal(err)
-	}
-	defer configFile.Close()
-	var config Config
-	bytes, _ := ioutil.ReadAll(configFile)
-	// Validate json data
-	valid := json.Valid(bytes)
-	if !valid {
-		log.Fatal("\nThe config file:\n", filepath, "\ndoes not contain valid JSON.")
-	}
-	json.Unmarshal(bytes, &config)
-	return config
-}
-
-func UpdateLastCall(c geolocation.Coordinates, path string) error {
-	// After call, save report.
-	newCallInfo := APICallInfo{
-		Time:        time.Now(),
-		Coordinates: c,
-	}
-	err := SaveCall(path, newCallInfo)
-	if err != nil {
-		fmt.Println("Error saving call info.\n", err)
 		return err
-	} else {
-		return nil
 	}
-}
-
-// Loads call information to determine whether
-// to retrieve forecast from server or disk
-func LoadCallInfo(path string) (APICallInfo, error) {
-	var lastCall APICallInfo
-	f, err := ioutil.ReadFile(path)
-	if err != nil {
-		return lastCall, err
-	}
-	err = json.Unmarshal(f, &lastCall)
-	if err != nil {
-		fmt.Println("Error unmarshalling last api call.\n", err)
-		return lastCall, err
-	}
-	return lastCall, nil
-}
-
-// Save info for future calls
-func SaveCall(path string, info APICallInfo) error {
-	c, err := json.Marshal(info)
-	if err != nil {
-		return err
-	}
-	err = ioutil.WriteFile(path, c, 0644)
-	if err != nil {
-		return err
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrCorrupt, path, err)
 	}
 	return nil
 }
 
-func SaveWeatherForecast(path string, f weather.Forecast) bool {
-	c, err := json.Marshal(f)
-	if err != nil {
-		fmt.Println("Error marshalling weather forecast before saving.\n", err)
-		return false
-	}
-	err = ioutil.WriteFile(path, c, 0644)
-	if err != nil {
-		return false
-	}
-	return true
-}
-
-func SaveAirForecast(path string, a []air.Forecast) bool {
-	c, err := json.Marshal(a)
+// Checks home folder for vaporwair config file to retrieve API keys.
+func GetConfig(filepath string) (Config, error) {
+	var config Config
+	bytes, err := ioutil.ReadFile(filepath)
 	if err != nil {
-		fmt.Println("Error marshalling air forecast before saving.\n", err)
-		return false
+		return config, err
 	}
-	err = ioutil.WriteFile(path, c, 0644)
-	if err != nil {
-		return false
+	// Validate json data
+	if !json.Valid(bytes) {
+		return config, fmt.Errorf("%w: %s does not contain valid JSON", ErrCorrupt, filepath)
 	}
-	return true
+	err = json.Unmarshal(bytes, &config)
+	return config, err
 }
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TemplatesDir holds named report templates, saved as NAME.tmpl.
+const TemplatesDir = VaporwairDir + "templates/"
+
+// ErrUnknownTemplate is returned when no template file or named template is found.
+var ErrUnknownTemplate = errors.New("no template file or named template")
+
+// LoadTemplate reads a report template. name is a path to a template file,
+// or the name of a template in the templates directory, with or without
+// its .tmpl extension.
+func LoadTemplate(homeDir, name string) (string, error) {
+	paths := []string{name}
+	if !strings.ContainsRune(name, filepath.Separator) {
+		named := homeDir + TemplatesDir + name
+		if filepath.Ext(name) != ".tmpl" {
+			named += ".tmpl"
+		}
+		paths = append(paths, named)
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			continue
+		}
+		b, err := ioutil.ReadFile(path)
+		if err == nil {
+			return string(b), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+}
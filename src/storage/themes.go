@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ThemesDir holds color themes, saved as NAME.json.
+const ThemesDir = VaporwairDir + "themes/"
+
+// ErrUnknownTheme is returned when no theme file has the configured name.
+var ErrUnknownTheme = errors.New("no theme named")
+
+// LoadTheme reads the named color theme, which maps report elements
+// to styles.
+func LoadTheme(homeDir, name string) (map[string]string, error) {
+	var t map[string]string
+	err := loadJSON(homeDir+ThemesDir+name+".json", &t)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTheme, name)
+	}
+	return t, err
+}
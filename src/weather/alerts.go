@@ -0,0 +1,106 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"time"
+)
+
+// AlertSource is implemented by services that issue severe weather alerts
+// apart from a forecast.
+type AlertSource interface {
+	GetAlerts(ctx context.Context, c geolocation.Coordinates) ([]Alert, error)
+}
+
+// AlertsWait is how long a forecast waits for alerts once it has arrived.
+const AlertsWait = 2 * time.Second
+
+// WithAlerts adds the alerts of a source to a provider's forecasts. Alerts
+// are a nicety: the forecast arrives without them if the source fails, or
+// is still working Wait after the forecast arrived. A zero Wait is AlertsWait.
+type WithAlerts struct {
+	Provider
+	Alerts AlertSource
+	Wait   time.Duration
+}
+
+// GetForecast retrieves the forecast and the alerts at the same time.
+// Alerts that are late are abandoned.
+func (p WithAlerts) GetForecast(ctx context.Context, c geolocation.Coordinates) (Forecast, error) {
+	alertsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	alertsChan := make(chan []Alert, 1)
+	go func() {
+		alerts, _ := p.Alerts.GetAlerts(alertsCtx, c)
+		alertsChan <- alerts
+	}()
+	wf, err := p.Provider.GetForecast(ctx, c)
+	if err != nil {
+		return wf, err
+	}
+	wait := p.Wait
+	if wait <= 0 {
+		wait = AlertsWait
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case alerts := <-alertsChan:
+		wf.Alerts = MergeAlerts(wf.Alerts, alerts)
+	case <-timer.C:
+	}
+	return wf, nil
+}
+
+// ID identifies an alert from one forecast to the next: its CAP identifier,
+// or its title and the time it was issued. A reissued alert has a new ID.
+func (a Alert) ID() string {
+	if a.Identifier != "" {
+		return a.Identifier
+	}
+	return fmt.Sprintf("%s@%.0f", a.Title, a.Time)
+}
+
+// same reports whether two alerts are the same. Providers that pass
+// NWS alerts along give them their own IDs, but keep the title and expiry.
+func same(a, b Alert) bool {
+	return a.ID() == b.ID() || (a.Title == b.Title && a.Expires == b.Expires)
+}
+
+// MergeAlerts adds the alerts in more that are not already in alerts.
+func MergeAlerts(alerts, more []Alert) []Alert {
+	merged := append([]Alert(nil), alerts...)
+	for _, a := range more {
+		if !hasAlert(merged, a) {
+			merged = append(merged, a)
+		}
+	}
+	return merged
+}
+
+func hasAlert(alerts []Alert, a Alert) bool {
+	for _, b := range alerts {
+		if same(a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether an alert is in effect at t. Alerts without an
+// expiry are in effect for as long as the provider reports them.
+func (a Alert) Active(t time.Time) bool {
+	return a.Expires == 0 || a.Expires > float64(t.Unix())
+}
+
+// ActiveAlerts returns the alerts in effect at t.
+func ActiveAlerts(alerts []Alert, t time.Time) []Alert {
+	var active []Alert
+	for _, a := range alerts {
+		if a.Active(t) {
+			active = append(active, a)
+		}
+	}
+	return active
+}
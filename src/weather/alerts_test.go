@@ -0,0 +1,101 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"testing"
+	"time"
+)
+
+func TestNWSGetAlerts(t *testing.T) {
+	ts := nwsStandIn(t)
+	defer ts.Close()
+
+	alerts, err := NWSAlerts{Address: ts.URL}.GetAlerts(context.Background(), nwsCoordinates)
+	if err != nil {
+		t.Fatalf("GetAlerts returned error: %v", err)
+	}
+	// The first wind advisory was updated, and the test message is dropped.
+	if len(alerts) != 2 {
+		t.Fatalf("GetAlerts returned %d alerts; want 2", len(alerts))
+	}
+	wind := alerts[0]
+	if wind.Title != "Wind Advisory" || wind.Severity != "advisory" || wind.Urgency != "expected" || wind.Certainty != "likely" {
+		t.Errorf("wind advisory = %+v", wind)
+	}
+	if want := time.Date(2020, 6, 22, 10, 0, 0, 0, time.UTC); wind.Expires != float64(want.Unix()) {
+		t.Errorf("wind advisory expires %v; want when the event ends, %v", wind.Expires, want)
+	}
+	if len(wind.Regions) != 2 || wind.Regions[1] != "Los Angeles County Beaches" {
+		t.Errorf("Regions = %q", wind.Regions)
+	}
+	// Statements are ranked by their CAP severity.
+	if beach := alerts[1]; beach.Severity != "warning" {
+		t.Errorf("beach hazards severity = %s; want warning", beach.Severity)
+	}
+}
+
+type failingAlerts struct{}
+
+func (failingAlerts) GetAlerts(context.Context, geolocation.Coordinates) ([]Alert, error) {
+	return nil, errors.New("unavailable")
+}
+
+// stalledAlerts never answers, and reports when it is abandoned.
+type stalledAlerts struct {
+	abandoned chan bool
+}
+
+func (s stalledAlerts) GetAlerts(ctx context.Context, _ geolocation.Coordinates) ([]Alert, error) {
+	<-ctx.Done()
+	s.abandoned <- true
+	return nil, ctx.Err()
+}
+
+func TestWithAlerts(t *testing.T) {
+	ts := nwsStandIn(t)
+	defer ts.Close()
+
+	p := WithAlerts{Provider: NWS{Address: ts.URL}, Alerts: NWSAlerts{Address: ts.URL}}
+	wf, err := p.GetForecast(context.Background(), nwsCoordinates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wf.Alerts) != 2 {
+		t.Errorf("forecast has %d alerts; want 2", len(wf.Alerts))
+	}
+
+	// The forecast arrives without alerts when they are unavailable.
+	p.Alerts = failingAlerts{}
+	if wf, err = p.GetForecast(context.Background(), nwsCoordinates); err != nil || len(wf.Alerts) != 0 {
+		t.Errorf("GetForecast = %d alerts, %v; want the forecast alone", len(wf.Alerts), err)
+	}
+
+	// A stalled alert service does not hold up the forecast.
+	stalled := stalledAlerts{abandoned: make(chan bool, 1)}
+	p.Alerts, p.Wait = stalled, 50*time.Millisecond
+	start := time.Now()
+	if wf, err = p.GetForecast(context.Background(), nwsCoordinates); err != nil || len(wf.Alerts) != 0 {
+		t.Errorf("GetForecast = %d alerts, %v; want the forecast alone", len(wf.Alerts), err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("GetForecast took %v with stalled alerts", d)
+	}
+	select {
+	case <-stalled.abandoned:
+	case <-time.After(time.Second):
+		t.Error("stalled alerts request was not abandoned")
+	}
+}
+
+func TestMergeAlerts(t *testing.T) {
+	// Dark Sky passes NWS alerts along with its own URIs.
+	darksky := Alert{Title: "Wind Advisory", Time: 100, Expires: 500, URI: "https://alerts.weather.gov/cap/x"}
+	nws := Alert{Title: "Wind Advisory", Time: 90, Expires: 500, Identifier: "urn:oid:1"}
+	flood := Alert{Title: "Flood Watch", Time: 90, Expires: 900, Identifier: "urn:oid:2"}
+	merged := MergeAlerts([]Alert{darksky}, []Alert{nws, flood, flood})
+	if len(merged) != 2 || merged[0].URI != darksky.URI || merged[1].Title != "Flood Watch" {
+		t.Errorf("MergeAlerts = %+v; want the Dark Sky advisory and the flood watch", merged)
+	}
+}
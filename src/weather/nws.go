@@ -0,0 +1,439 @@
+package weather
+
+import (
+	"context"
+	"github.com/jeff-bruemmer/vaporwair/src/dialer"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const NWSAddress = "https://api.weather.gov"
+
+// NWS retrieves forecasts from the National Weather Service API.
+// The service covers the United States only and requires no API key.
+type NWS struct {
+	Address string
+}
+
+// nwsPoint holds the links the points endpoint returns for a location.
+type nwsPoint struct {
+	Properties struct {
+		Forecast            string `json:"forecast"`
+		ForecastHourly      string `json:"forecastHourly"`
+		ObservationStations string `json:"observationStations"`
+		TimeZone            string `json:"timeZone"`
+	} `json:"properties"`
+}
+
+// nwsValue is a quantitative value. Value is nil when the station
+// did not report a measurement.
+type nwsValue struct {
+	UnitCode string   `json:"unitCode"`
+	Value    *float64 `json:"value"`
+}
+
+type nwsPeriod struct {
+	Number                     int       `json:"number"`
+	Name                       string    `json:"name"`
+	StartTime                  time.Time `json:"startTime"`
+	EndTime                    time.Time `json:"endTime"`
+	IsDaytime                  bool      `json:"isDaytime"`
+	Temperature                float64   `json:"temperature"`
+	TemperatureUnit            string    `json:"temperatureUnit"`
+	WindSpeed                  string    `json:"windSpeed"`
+	WindDirection              string    `json:"windDirection"`
+	Icon                       string    `json:"icon"`
+	ShortForecast              string    `json:"shortForecast"`
+	DetailedForecast           string    `json:"detailedForecast"`
+	ProbabilityOfPrecipitation nwsValue  `json:"probabilityOfPrecipitation"`
+	Dewpoint                   nwsValue  `json:"dewpoint"`
+	RelativeHumidity           nwsValue  `json:"relativeHumidity"`
+}
+
+type nwsForecast struct {
+	Properties struct {
+		Periods []nwsPeriod `json:"periods"`
+	} `json:"properties"`
+}
+
+type nwsStations struct {
+	ObservationStations []string `json:"observationStations"`
+}
+
+type nwsObservation struct {
+	Properties struct {
+		Timestamp          time.Time `json:"timestamp"`
+		TextDescription    string    `json:"textDescription"`
+		Icon               string    `json:"icon"`
+		Temperature        nwsValue  `json:"temperature"`
+		Dewpoint           nwsValue  `json:"dewpoint"`
+		WindDirection      nwsValue  `json:"windDirection"`
+		WindSpeed          nwsValue  `json:"windSpeed"`
+		BarometricPressure nwsValue  `json:"barometricPressure"`
+		Visibility         nwsValue  `json:"visibility"`
+		RelativeHumidity   nwsValue  `json:"relativeHumidity"`
+		HeatIndex          nwsValue  `json:"heatIndex"`
+		WindChill          nwsValue  `json:"windChill"`
+		CloudLayers        []struct {
+			Amount string `json:"amount"`
+		} `json:"cloudLayers"`
+	} `json:"properties"`
+}
+
+// Sky cover reported by stations, as the fraction of the sky each amount
+// covers: the middle of its range of eighths.
+var skyCover = map[string]float64{
+	"SKC": 0,
+	"CLR": 0,
+	"FEW": 1.5 / 8,
+	"SCT": 3.5 / 8,
+	"BKN": 6.0 / 8,
+	"OVC": 1,
+	"VV":  1,
+}
+
+// Compass points used by the NWS for wind direction, in bearing order.
+var compass = []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// BuildNWSPointURL creates the address of the points endpoint, which links
+// to the gridpoint forecasts and observation stations for the coordinates.
+// The NWS only accepts four decimal places.
+func BuildNWSPointURL(addr string, c geolocation.Coordinates) string {
+	return addr + "/points/" + fourPlaces(c.Latitude) + "," + fourPlaces(c.Longitude)
+}
+
+// GetForecast walks from the points endpoint to the daily and hourly gridpoint
+// forecasts and the latest observation from the nearest station, and maps
+// them onto a Forecast in US units.
+func (n NWS) GetForecast(ctx context.Context, c geolocation.Coordinates) (Forecast, error) {
+	var wf Forecast
+	var p nwsPoint
+	if err := nwsGet(ctx, BuildNWSPointURL(n.Address, c), &p); err != nil {
+		return wf, err
+	}
+
+	// The three remaining calls do not depend on each other.
+	var daily, hourly nwsForecast
+	dailyChan := make(chan error)
+	hourlyChan := make(chan error)
+	obsChan := make(chan *nwsObservation)
+	go func() {
+		dailyChan <- nwsGet(ctx, p.Properties.Forecast, &daily)
+	}()
+	go func() {
+		hourlyChan <- nwsGet(ctx, p.Properties.ForecastHourly, &hourly)
+	}()
+	go func() {
+		obsChan <- latestObservation(ctx, p.Properties.ObservationStations)
+	}()
+	dailyErr := <-dailyChan
+	hourlyErr := <-hourlyChan
+	obs := <-obsChan
+	if dailyErr != nil {
+		return wf, dailyErr
+	}
+	if hourlyErr != nil {
+		return wf, hourlyErr
+	}
+
+	wf.Latitude = c.Latitude
+	wf.Longitude = c.Longitude
+	wf.Timezone = p.Properties.TimeZone
+	wf.Hourly = nwsHourly(hourly.Properties.Periods)
+	wf.Daily = nwsDaily(daily.Properties.Periods, wf.Hourly.Data, c.Latitude, c.Longitude)
+	if len(hourly.Properties.Periods) > 0 {
+		_, offset := hourly.Properties.Periods[0].StartTime.Zone()
+		wf.Offset = float64(offset) / 3600
+	}
+	if len(wf.Hourly.Data) > 0 {
+		wf.Currently = wf.Hourly.Data[0]
+	}
+	if obs != nil {
+		wf.Currently = nwsCurrently(*obs, wf.Currently)
+	}
+	wf.Flags.Sources = []string{NWSName}
+	wf.Flags.Units = string(US)
+	return wf, nil
+}
+
+// nwsGet dials an NWS endpoint and decodes the JSON response into v.
+func nwsGet(ctx context.Context, addr string, v interface{}) error {
+	return nwsGetAs(ctx, addr, "", v)
+}
+
+// nwsGetAs is nwsGet asking for a format other than the default GeoJSON,
+// such as JSON-LD.
+func nwsGetAs(ctx context.Context, addr, accept string, v interface{}) error {
+	header := http.Header{}
+	if accept != "" {
+		header.Set("Accept", accept)
+	}
+	resp, err := dialer.NetReqHeader(ctx, addr, 10, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := dialer.CheckStatus(resp, "National Weather Service"); err != nil {
+		return err
+	}
+	return dialer.DecodeJSON(resp.Body, "National Weather Service", v)
+}
+
+// latestObservation returns the most recent observation from the closest
+// station, or nil if there is none. Observations are a nicety: the forecast
+// falls back to the current hour when a station is unavailable.
+func latestObservation(ctx context.Context, stationsAddr string) *nwsObservation {
+	var s nwsStations
+	if err := nwsGet(ctx, stationsAddr, &s); err != nil || len(s.ObservationStations) == 0 {
+		return nil
+	}
+	var o nwsObservation
+	if err := nwsGet(ctx, s.ObservationStations[0]+"/observations/latest", &o); err != nil {
+		return nil
+	}
+	return &o
+}
+
+// nwsHourly maps hourly periods onto data points.
+func nwsHourly(periods []nwsPeriod) DataBlock {
+	var b DataBlock
+	for _, p := range periods {
+		dp := DataPoint{
+			Time:                float64(p.StartTime.Unix()),
+			Summary:             p.ShortForecast,
+			Icon:                p.Icon,
+			Temperature:         periodFahrenheit(p),
+			ApparentTemperature: periodFahrenheit(p),
+			PrecipProbability:   percent(p.ProbabilityOfPrecipitation),
+			PrecipType:          precipType(p.ShortForecast),
+			DewPoint:            fahrenheit(p.Dewpoint),
+			Humidity:            percent(p.RelativeHumidity),
+			WindSpeed:           parseWindSpeed(p.WindSpeed),
+			WindBearing:         bearing(p.WindDirection),
+		}
+		b.Data = append(b.Data, dp)
+	}
+	if len(periods) > 0 {
+		b.Summary = periods[0].ShortForecast
+	}
+	return b
+}
+
+// nwsDaily folds the alternating day and night periods into one data point per
+// calendar day. The hourly data supplies the minimum and maximum temperatures
+// and their times; the periods are used for days beyond the hourly forecast.
+func nwsDaily(periods []nwsPeriod, hours []DataPoint, lat, lon float64) DataBlock {
+	var b DataBlock
+	if len(periods) > 0 {
+		b.Summary = periods[0].DetailedForecast
+	}
+	index := make(map[string]int)
+	for _, p := range periods {
+		date := p.StartTime.Format("2006-01-02")
+		i, ok := index[date]
+		if !ok {
+			start := p.StartTime
+			midnight := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+			rise, set := SunTimes(start, lat, lon)
+			b.Data = append(b.Data, DataPoint{
+				Time:           float64(midnight.Unix()),
+				Summary:        p.ShortForecast,
+				Icon:           p.Icon,
+				SunriseTime:    rise,
+				SunsetTime:     set,
+				TemperatureMin: periodFahrenheit(p),
+				TemperatureMax: periodFahrenheit(p),
+			})
+			i = len(b.Data) - 1
+			index[date] = i
+		}
+		day := &b.Data[i]
+		t := periodFahrenheit(p)
+		if p.IsDaytime {
+			day.Summary = p.ShortForecast
+			day.Icon = p.Icon
+			day.TemperatureMax = t
+			day.TemperatureMaxTime = float64(p.StartTime.Unix())
+		} else {
+			day.TemperatureMin = t
+			day.TemperatureMinTime = float64(p.StartTime.Unix())
+		}
+		if pp := percent(p.ProbabilityOfPrecipitation); pp >= day.PrecipProbability {
+			day.PrecipProbability = pp
+			day.PrecipType = precipType(p.ShortForecast)
+		}
+		if ws := parseWindSpeed(p.WindSpeed); ws > day.WindSpeed {
+			day.WindSpeed = ws
+			day.WindBearing = bearing(p.WindDirection)
+		}
+		if h := percent(p.RelativeHumidity); h > day.Humidity {
+			day.Humidity = h
+		}
+	}
+
+	// Refine temperatures with the hourly forecast where it is available.
+	// Dates are compared in the forecast location's zone.
+	if len(periods) == 0 {
+		return b
+	}
+	loc := periods[0].StartTime.Location()
+	for i := range b.Data {
+		day := &b.Data[i]
+		date := time.Unix(int64(day.Time), 0).In(loc).Format("2006-01-02")
+		first := true
+		for _, h := range hours {
+			if time.Unix(int64(h.Time), 0).In(loc).Format("2006-01-02") != date {
+				continue
+			}
+			if first || h.Temperature < day.TemperatureMin {
+				day.TemperatureMin = h.Temperature
+				day.TemperatureMinTime = h.Time
+			}
+			if first || h.Temperature > day.TemperatureMax {
+				day.TemperatureMax = h.Temperature
+				day.TemperatureMaxTime = h.Time
+			}
+			first = false
+		}
+	}
+	return b
+}
+
+// nwsCurrently overlays a station observation onto the current hour.
+// Observations are reported in SI units.
+func nwsCurrently(o nwsObservation, dp DataPoint) DataPoint {
+	p := o.Properties
+	if p.Temperature.Value == nil {
+		return dp
+	}
+	dp.Time = float64(p.Timestamp.Unix())
+	if p.TextDescription != "" {
+		dp.Summary = p.TextDescription
+	}
+	dp.Temperature = fahrenheit(p.Temperature)
+	dp.ApparentTemperature = dp.Temperature
+	if p.HeatIndex.Value != nil {
+		dp.ApparentTemperature = fahrenheit(p.HeatIndex)
+	} else if p.WindChill.Value != nil {
+		dp.ApparentTemperature = fahrenheit(p.WindChill)
+	}
+	if p.Dewpoint.Value != nil {
+		dp.DewPoint = fahrenheit(p.Dewpoint)
+	}
+	if p.RelativeHumidity.Value != nil {
+		dp.Humidity = percent(p.RelativeHumidity)
+	}
+	if p.WindSpeed.Value != nil {
+		dp.WindSpeed = mph(p.WindSpeed)
+	}
+	if p.WindDirection.Value != nil {
+		dp.WindBearing = *p.WindDirection.Value
+	}
+	if p.BarometricPressure.Value != nil {
+		// Pascals to millibars.
+		dp.Pressure = *p.BarometricPressure.Value / 100
+	}
+	if p.Visibility.Value != nil {
+		// Meters to miles.
+		dp.Visibility = *p.Visibility.Value / 1609.344
+	}
+	// The sky is as covered as its thickest layer.
+	for _, l := range p.CloudLayers {
+		if c, ok := skyCover[l.Amount]; ok && c > dp.CloudCover {
+			dp.CloudCover = c
+		}
+	}
+	return dp
+}
+
+// fourPlaces rounds a coordinate to the precision the NWS accepts.
+func fourPlaces(c float64) string {
+	return strconv.FormatFloat(c, 'f', 4, 64)
+}
+
+// periodFahrenheit returns the period temperature in degrees Fahrenheit.
+func periodFahrenheit(p nwsPeriod) float64 {
+	if p.TemperatureUnit == "C" {
+		return p.Temperature*9/5 + 32
+	}
+	return p.Temperature
+}
+
+// fahrenheit converts a temperature value to degrees Fahrenheit.
+func fahrenheit(v nwsValue) float64 {
+	if v.Value == nil {
+		return 0
+	}
+	if strings.HasSuffix(v.UnitCode, "degC") {
+		return *v.Value*9/5 + 32
+	}
+	return *v.Value
+}
+
+// mph converts a speed value to miles per hour.
+func mph(v nwsValue) float64 {
+	if v.Value == nil {
+		return 0
+	}
+	switch {
+	case strings.HasSuffix(v.UnitCode, "km_h-1"):
+		return *v.Value * 0.621371
+	case strings.HasSuffix(v.UnitCode, "m_s-1"):
+		return *v.Value * 2.236936
+	}
+	return *v.Value
+}
+
+// percent converts a percentage value to a decimal, as Dark Sky reports it.
+func percent(v nwsValue) float64 {
+	if v.Value == nil {
+		return 0
+	}
+	return *v.Value / 100
+}
+
+// parseWindSpeed reads speeds such as "10 mph" or "5 to 10 mph",
+// averaging ranges.
+func parseWindSpeed(s string) float64 {
+	var total float64
+	var n int
+	for _, field := range strings.Fields(s) {
+		if v, err := strconv.ParseFloat(field, 64); err == nil {
+			total += v
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / float64(n)
+}
+
+// bearing converts a compass point to degrees.
+func bearing(direction string) float64 {
+	for i, point := range compass {
+		if point == direction {
+			return float64(i) * 22.5
+		}
+	}
+	return 0
+}
+
+// precipType infers the type of precipitation from a short forecast.
+func precipType(summary string) string {
+	s := strings.ToLower(summary)
+	switch {
+	case strings.Contains(s, "snow") || strings.Contains(s, "flurries"):
+		return "snow"
+	case strings.Contains(s, "sleet") || strings.Contains(s, "freezing") || strings.Contains(s, "ice"):
+		return "sleet"
+	case strings.Contains(s, "rain") || strings.Contains(s, "showers") ||
+		strings.Contains(s, "drizzle") || strings.Contains(s, "thunderstorms"):
+		return "rain"
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Recorded NWS responses, keyed by request path.
+var nwsRecordings = map[string]string{
+	"/points/34.0308,-118.4730":              "testdata/nws/points.json",
+	"/gridpoints/LOX/146,44/forecast":        "testdata/nws/forecast.json",
+	"/gridpoints/LOX/146,44/forecast/hourly": "testdata/nws/forecast-hourly.json",
+	"/gridpoints/LOX/146,44/stations":        "testdata/nws/stations.json",
+	"/stations/KSMO/observations/latest":     "testdata/nws/observation.json",
+	"/alerts/active":                         "testdata/nws/alerts.json",
+}
+
+var nwsCoordinates = geolocation.Coordinates{
+	Latitude:  34.0308,
+	Longitude: -118.473,
+}
+
+// nwsStandIn serves the recordings, pointing their links at itself.
+func nwsStandIn(t *testing.T) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ok := nwsRecordings[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Write([]byte(strings.Replace(string(b), NWSAddress, ts.URL, -1)))
+	}))
+	return ts
+}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestNWSGetForecast(t *testing.T) {
+	ts := nwsStandIn(t)
+	defer ts.Close()
+
+	wf, err := NWS{Address: ts.URL}.GetForecast(context.Background(), nwsCoordinates)
+	if err != nil {
+		t.Fatalf("GetForecast returned error: %v", err)
+	}
+	if wf.Timezone != "America/Los_Angeles" {
+		t.Errorf("Timezone = %s; want America/Los_Angeles", wf.Timezone)
+	}
+	if wf.Offset != -7 {
+		t.Errorf("Offset = %v; want -7", wf.Offset)
+	}
+
+	// Currently comes from the KSMO observation, converted to US units.
+	if wf.Currently.Summary != "Clear" {
+		t.Errorf("Currently.Summary = %s; want Clear", wf.Currently.Summary)
+	}
+	if !near(wf.Currently.Temperature, 73.04, 0.01) {
+		t.Errorf("Currently.Temperature = %v; want 73.04", wf.Currently.Temperature)
+	}
+	if !near(wf.Currently.WindSpeed, 10.29, 0.01) {
+		t.Errorf("Currently.WindSpeed = %v; want 10.29", wf.Currently.WindSpeed)
+	}
+	if !near(wf.Currently.Pressure, 1014.9, 0.01) {
+		t.Errorf("Currently.Pressure = %v; want 1014.9", wf.Currently.Pressure)
+	}
+
+	if len(wf.Hourly.Data) != 4 {
+		t.Fatalf("len(Hourly.Data) = %d; want 4", len(wf.Hourly.Data))
+	}
+	h := wf.Hourly.Data[2]
+	if h.Temperature != 62 || h.PrecipProbability != 0.2 || h.PrecipType != "rain" || h.WindBearing != 270 {
+		t.Errorf("Hourly.Data[2] = %+v; want 62 °F, 0.2 rain, bearing 270", h)
+	}
+
+	if len(wf.Daily.Data) != 2 {
+		t.Fatalf("len(Daily.Data) = %d; want 2", len(wf.Daily.Data))
+	}
+	today := wf.Daily.Data[0]
+	if today.Summary != "Sunny" {
+		t.Errorf("Daily.Data[0].Summary = %s; want Sunny", today.Summary)
+	}
+	// Hourly temperatures refine the period temperatures.
+	if today.TemperatureMax != 75 || today.TemperatureMin != 62 {
+		t.Errorf("Daily.Data[0] min/max = %v/%v; want 62/75", today.TemperatureMin, today.TemperatureMax)
+	}
+	if today.PrecipProbability != 0.2 || today.WindSpeed != 7.5 {
+		t.Errorf("Daily.Data[0] precip/wind = %v/%v; want 0.2/7.5", today.PrecipProbability, today.WindSpeed)
+	}
+	if today.SunriseTime == 0 || today.SunsetTime == 0 {
+		t.Errorf("Daily.Data[0] sun times not set")
+	}
+	if wf.Daily.Summary != "Patchy fog before 10am, then sunny, with a high near 74." {
+		t.Errorf("Daily.Summary = %s", wf.Daily.Summary)
+	}
+}
+
+func TestNWSGetForecastStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Data Unavailable For Requested Point", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	if _, err := (NWS{Address: ts.URL}).GetForecast(context.Background(), nwsCoordinates); err == nil {
+		t.Error("GetForecast for an unsupported point returned no error")
+	}
+}
+
+func TestSunTimes(t *testing.T) {
+	// Santa Monica on the summer solstice: sunrise 05:42, sunset 20:08 PDT.
+	pdt := time.FixedZone("PDT", -7*3600)
+	day := time.Date(2020, 6, 21, 0, 0, 0, 0, pdt)
+	rise, set := SunTimes(day, 34.0308, -118.473)
+	wantRise := time.Date(2020, 6, 21, 5, 42, 0, 0, pdt).Unix()
+	wantSet := time.Date(2020, 6, 21, 20, 8, 0, 0, pdt).Unix()
+	if !near(rise, float64(wantRise), 180) {
+		t.Errorf("sunrise = %v; want %v", time.Unix(int64(rise), 0).In(pdt), time.Unix(wantRise, 0).In(pdt))
+	}
+	if !near(set, float64(wantSet), 180) {
+		t.Errorf("sunset = %v; want %v", time.Unix(int64(set), 0).In(pdt), time.Unix(wantSet, 0).In(pdt))
+	}
+}
+
+func TestNWSCloudCover(t *testing.T) {
+	var o nwsObservation
+	body := `{"properties": {"temperature": {"unitCode": "wmoUnit:degC", "value": 20},
+		"cloudLayers": [{"amount": "FEW"}, {"amount": "BKN"}, {"amount": "SCT"}]}}`
+	if err := json.Unmarshal([]byte(body), &o); err != nil {
+		t.Fatal(err)
+	}
+	if c := nwsCurrently(o, DataPoint{}).CloudCover; c != 0.75 {
+		t.Errorf("CloudCover = %v; want 0.75, the broken layer", c)
+	}
+}
@@ -0,0 +1,126 @@
+package weather
+
+import (
+	"context"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"strings"
+	"time"
+)
+
+// NWSAlerts retrieves the active alerts of the National Weather Service
+// for a location, whichever provider its forecast comes from. Locations
+// outside the United States have none.
+type NWSAlerts struct {
+	Address string
+}
+
+// nwsAlert is an alert in the Common Alerting Protocol, as JSON-LD.
+type nwsAlert struct {
+	URI         string     `json:"@id"`
+	Identifier  string     `json:"id"`
+	AreaDesc    string     `json:"areaDesc"`
+	Sent        time.Time  `json:"sent"`
+	Expires     time.Time  `json:"expires"`
+	Ends        *time.Time `json:"ends"`
+	Status      string     `json:"status"`
+	MessageType string     `json:"messageType"`
+	Severity    string     `json:"severity"`
+	Certainty   string     `json:"certainty"`
+	Urgency     string     `json:"urgency"`
+	Event       string     `json:"event"`
+	Description string     `json:"description"`
+	Instruction string     `json:"instruction"`
+	References  []struct {
+		Identifier string `json:"identifier"`
+	} `json:"references"`
+}
+
+type nwsAlerts struct {
+	Graph []nwsAlert `json:"@graph"`
+}
+
+// BuildNWSAlertsURL creates the address of the active alerts for the coordinates.
+func BuildNWSAlertsURL(addr string, c geolocation.Coordinates) string {
+	return addr + "/alerts/active?point=" + fourPlaces(c.Latitude) + "," + fourPlaces(c.Longitude)
+}
+
+// GetAlerts retrieves the alerts in effect at the coordinates.
+func (n NWSAlerts) GetAlerts(ctx context.Context, c geolocation.Coordinates) ([]Alert, error) {
+	var a nwsAlerts
+	if err := nwsGetAs(ctx, BuildNWSAlertsURL(n.Address, c), "application/ld+json", &a); err != nil {
+		return nil, err
+	}
+	return alertsOf(a.Graph), nil
+}
+
+// alertsOf maps CAP alerts onto Alerts. Tests, exercises and cancellations
+// are left out, as are duplicates and alerts that a later one updates.
+func alertsOf(graph []nwsAlert) []Alert {
+	replaced := map[string]bool{}
+	for _, a := range graph {
+		for _, r := range a.References {
+			replaced[r.Identifier] = true
+		}
+	}
+	seen := map[string]bool{}
+	var alerts []Alert
+	for _, a := range graph {
+		if a.Status != "Actual" || a.MessageType == "Cancel" || replaced[a.Identifier] || seen[a.Identifier] {
+			continue
+		}
+		seen[a.Identifier] = true
+		alerts = append(alerts, a.alert())
+	}
+	return alerts
+}
+
+func (a nwsAlert) alert() Alert {
+	// The event ends after the message expires, if the NWS knows when.
+	expires := a.Expires
+	if a.Ends != nil {
+		expires = *a.Ends
+	}
+	description := strings.TrimSpace(a.Description)
+	if a.Instruction != "" {
+		description += "\n\n" + strings.TrimSpace(a.Instruction)
+	}
+	var regions []string
+	if a.AreaDesc != "" {
+		regions = strings.Split(a.AreaDesc, "; ")
+	}
+	return Alert{
+		Title:       a.Event,
+		Regions:     regions,
+		Severity:    alertSeverity(a.Event, a.Severity),
+		Urgency:     strings.ToLower(a.Urgency),
+		Certainty:   strings.ToLower(a.Certainty),
+		Description: description,
+		Time:        unix(a.Sent),
+		Expires:     unix(expires),
+		URI:         a.URI,
+		Identifier:  a.Identifier,
+	}
+}
+
+// alertSeverity names the severity of an alert as Dark Sky does: warning,
+// watch or advisory. Most events are named for theirs; the others are
+// ranked by their CAP severity.
+func alertSeverity(event, severity string) string {
+	for _, s := range []string{"warning", "watch", "advisory"} {
+		if strings.HasSuffix(strings.ToLower(event), s) {
+			return s
+		}
+	}
+	if severity == "Extreme" || severity == "Severe" {
+		return "warning"
+	}
+	return "advisory"
+}
+
+// unix converts a time to Unix seconds. The zero time is 0.
+func unix(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.Unix())
+}
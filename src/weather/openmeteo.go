@@ -0,0 +1,350 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"github.com/jeff-bruemmer/vaporwair/src/dialer"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"strings"
+)
+
+const OpenMeteoAddress = "https://api.open-meteo.com/v1/forecast"
+
+// Variables requested from Open-Meteo for each block.
+const (
+	openMeteoCurrent = "temperature_2m,apparent_temperature,relative_humidity_2m,dew_point_2m," +
+		"precipitation,weather_code,cloud_cover,pressure_msl,visibility,wind_speed_10m,wind_direction_10m,uv_index"
+	openMeteoHourly = "temperature_2m,apparent_temperature,relative_humidity_2m,dew_point_2m," +
+		"precipitation_probability,precipitation,weather_code,cloud_cover,pressure_msl,visibility," +
+		"wind_speed_10m,wind_direction_10m,uv_index"
+	openMeteoDaily = "weather_code,temperature_2m_max,temperature_2m_min,sunrise,sunset,uv_index_max," +
+		"precipitation_sum,precipitation_probability_max,wind_speed_10m_max,wind_direction_10m_dominant," +
+		"relative_humidity_2m_mean"
+)
+
+// OpenMeteo retrieves forecasts from the Open-Meteo API.
+// The service covers the whole world and requires no API key.
+type OpenMeteo struct {
+	Address string
+}
+
+type openMeteoCurrentBlock struct {
+	Time                float64 `json:"time"`
+	Temperature         float64 `json:"temperature_2m"`
+	ApparentTemperature float64 `json:"apparent_temperature"`
+	RelativeHumidity    float64 `json:"relative_humidity_2m"`
+	DewPoint            float64 `json:"dew_point_2m"`
+	Precipitation       float64 `json:"precipitation"`
+	WeatherCode         int     `json:"weather_code"`
+	CloudCover          float64 `json:"cloud_cover"`
+	Pressure            float64 `json:"pressure_msl"`
+	Visibility          float64 `json:"visibility"`
+	WindSpeed           float64 `json:"wind_speed_10m"`
+	WindDirection       float64 `json:"wind_direction_10m"`
+	UVIndex             float64 `json:"uv_index"`
+}
+
+// Open-Meteo returns each hourly and daily variable as its own array,
+// indexed alongside Time.
+type openMeteoHourlyBlock struct {
+	Time                     []float64 `json:"time"`
+	Temperature              []float64 `json:"temperature_2m"`
+	ApparentTemperature      []float64 `json:"apparent_temperature"`
+	RelativeHumidity         []float64 `json:"relative_humidity_2m"`
+	DewPoint                 []float64 `json:"dew_point_2m"`
+	PrecipitationProbability []float64 `json:"precipitation_probability"`
+	Precipitation            []float64 `json:"precipitation"`
+	WeatherCode              []int     `json:"weather_code"`
+	CloudCover               []float64 `json:"cloud_cover"`
+	Pressure                 []float64 `json:"pressure_msl"`
+	Visibility               []float64 `json:"visibility"`
+	WindSpeed                []float64 `json:"wind_speed_10m"`
+	WindDirection            []float64 `json:"wind_direction_10m"`
+	UVIndex                  []float64 `json:"uv_index"`
+}
+
+type openMeteoDailyBlock struct {
+	Time                        []float64 `json:"time"`
+	WeatherCode                 []int     `json:"weather_code"`
+	TemperatureMax              []float64 `json:"temperature_2m_max"`
+	TemperatureMin              []float64 `json:"temperature_2m_min"`
+	Sunrise                     []float64 `json:"sunrise"`
+	Sunset                      []float64 `json:"sunset"`
+	UVIndexMax                  []float64 `json:"uv_index_max"`
+	PrecipitationSum            []float64 `json:"precipitation_sum"`
+	PrecipitationProbabilityMax []float64 `json:"precipitation_probability_max"`
+	WindSpeedMax                []float64 `json:"wind_speed_10m_max"`
+	WindDirectionDominant       []float64 `json:"wind_direction_10m_dominant"`
+	RelativeHumidityMean        []float64 `json:"relative_humidity_2m_mean"`
+}
+
+type openMeteoForecast struct {
+	Latitude         float64               `json:"latitude"`
+	Longitude        float64               `json:"longitude"`
+	Timezone         string                `json:"timezone"`
+	UTCOffsetSeconds float64               `json:"utc_offset_seconds"`
+	CurrentUnits     map[string]string     `json:"current_units"`
+	Current          openMeteoCurrentBlock `json:"current"`
+	HourlyUnits      map[string]string     `json:"hourly_units"`
+	Hourly           openMeteoHourlyBlock  `json:"hourly"`
+	Daily            openMeteoDailyBlock   `json:"daily"`
+}
+
+// weatherCode describes a WMO weather interpretation code
+// in the terms Dark Sky used.
+type weatherCode struct {
+	Summary    string
+	Icon       string
+	PrecipType string
+}
+
+var weatherCodes = map[int]weatherCode{
+	0:  {"Clear", "clear-day", ""},
+	1:  {"Mostly clear", "clear-day", ""},
+	2:  {"Partly cloudy", "partly-cloudy-day", ""},
+	3:  {"Overcast", "cloudy", ""},
+	45: {"Foggy", "fog", ""},
+	48: {"Freezing fog", "fog", ""},
+	51: {"Light drizzle", "rain", "rain"},
+	53: {"Drizzle", "rain", "rain"},
+	55: {"Heavy drizzle", "rain", "rain"},
+	56: {"Light freezing drizzle", "sleet", "sleet"},
+	57: {"Freezing drizzle", "sleet", "sleet"},
+	61: {"Light rain", "rain", "rain"},
+	63: {"Rain", "rain", "rain"},
+	65: {"Heavy rain", "rain", "rain"},
+	66: {"Light freezing rain", "sleet", "sleet"},
+	67: {"Freezing rain", "sleet", "sleet"},
+	71: {"Light snow", "snow", "snow"},
+	73: {"Snow", "snow", "snow"},
+	75: {"Heavy snow", "snow", "snow"},
+	77: {"Snow grains", "snow", "snow"},
+	80: {"Light rain showers", "rain", "rain"},
+	81: {"Rain showers", "rain", "rain"},
+	82: {"Violent rain showers", "rain", "rain"},
+	85: {"Light snow showers", "snow", "snow"},
+	86: {"Snow showers", "snow", "snow"},
+	95: {"Thunderstorms", "thunderstorm", "rain"},
+	96: {"Thunderstorms with hail", "thunderstorm", "rain"},
+	99: {"Thunderstorms with heavy hail", "thunderstorm", "rain"},
+}
+
+// BuildOpenMeteoURL creates http address for dialer to call Open-Meteo API.
+// Values are requested in US units and Unix times to match Dark Sky.
+func BuildOpenMeteoURL(addr string, c geolocation.Coordinates) string {
+	return addr +
+		"?latitude=" + geolocation.FormatCoordinate(c.Latitude) +
+		"&longitude=" + geolocation.FormatCoordinate(c.Longitude) +
+		"&current=" + openMeteoCurrent +
+		"&hourly=" + openMeteoHourly +
+		"&daily=" + openMeteoDaily +
+		"&temperature_unit=fahrenheit" +
+		"&wind_speed_unit=mph" +
+		"&precipitation_unit=inch" +
+		"&timeformat=unixtime" +
+		"&timezone=auto"
+}
+
+// GetForecast dials the Open-Meteo API and maps its response onto a Forecast.
+func (o OpenMeteo) GetForecast(ctx context.Context, c geolocation.Coordinates) (Forecast, error) {
+	var om openMeteoForecast
+	addr := BuildOpenMeteoURL(o.Address, c)
+	resp, err := dialer.NetReq(ctx, addr, 10, false)
+	if err != nil {
+		return Forecast{}, err
+	}
+	defer resp.Body.Close()
+	if err := dialer.CheckStatus(resp, "Open-Meteo"); err != nil {
+		return Forecast{}, err
+	}
+	if err := dialer.DecodeJSON(resp.Body, "Open-Meteo", &om); err != nil {
+		return Forecast{}, err
+	}
+	return om.toForecast(), nil
+}
+
+// toForecast maps the Open-Meteo blocks onto the Dark Sky schema.
+func (om openMeteoForecast) toForecast() Forecast {
+	var wf Forecast
+	wf.Latitude = om.Latitude
+	wf.Longitude = om.Longitude
+	wf.Timezone = om.Timezone
+	wf.Offset = om.UTCOffsetSeconds / 3600
+
+	cur := om.Current
+	code := weatherCodes[cur.WeatherCode]
+	wf.Currently = DataPoint{
+		Time:                cur.Time,
+		Summary:             code.Summary,
+		Icon:                code.Icon,
+		PrecipIntensity:     cur.Precipitation,
+		Temperature:         cur.Temperature,
+		ApparentTemperature: cur.ApparentTemperature,
+		DewPoint:            cur.DewPoint,
+		WindSpeed:           cur.WindSpeed,
+		WindBearing:         cur.WindDirection,
+		CloudCover:          cur.CloudCover / 100,
+		Humidity:            cur.RelativeHumidity / 100,
+		Pressure:            cur.Pressure,
+		Visibility:          toMiles(cur.Visibility, om.CurrentUnits["visibility"]),
+		UVIndex:             cur.UVIndex,
+	}
+	if cur.Precipitation > 0 {
+		wf.Currently.PrecipType = code.PrecipType
+	}
+
+	wf.Hourly = om.hourly()
+	wf.Daily = om.daily(wf.Hourly.Data)
+	wf.Flags.Sources = []string{OpenMeteoName}
+	wf.Flags.Units = string(US)
+	return wf
+}
+
+// hourly maps the hourly arrays onto data points, starting with the current hour.
+// Open-Meteo always begins the hourly block at midnight.
+func (om openMeteoForecast) hourly() DataBlock {
+	var b DataBlock
+	h := om.Hourly
+	start := om.Current.Time - 3600
+	for i, t := range h.Time {
+		if t <= start {
+			continue
+		}
+		code := weatherCodes[at(h.WeatherCode, i)]
+		dp := DataPoint{
+			Time:                t,
+			Summary:             code.Summary,
+			Icon:                code.Icon,
+			PrecipIntensity:     valueAt(h.Precipitation, i),
+			PrecipProbability:   valueAt(h.PrecipitationProbability, i) / 100,
+			Temperature:         valueAt(h.Temperature, i),
+			ApparentTemperature: valueAt(h.ApparentTemperature, i),
+			DewPoint:            valueAt(h.DewPoint, i),
+			WindSpeed:           valueAt(h.WindSpeed, i),
+			WindBearing:         valueAt(h.WindDirection, i),
+			CloudCover:          valueAt(h.CloudCover, i) / 100,
+			Humidity:            valueAt(h.RelativeHumidity, i) / 100,
+			Pressure:            valueAt(h.Pressure, i),
+			Visibility:          toMiles(valueAt(h.Visibility, i), om.HourlyUnits["visibility"]),
+			UVIndex:             valueAt(h.UVIndex, i),
+		}
+		if dp.PrecipProbability > 0 {
+			dp.PrecipType = code.PrecipType
+		}
+		b.Data = append(b.Data, dp)
+	}
+	b.Summary = dominantSummary(firstHours(b.Data, 12)) + " for the next few hours"
+	return b
+}
+
+// daily maps the daily arrays onto data points. Open-Meteo does not report
+// when the minimum and maximum temperatures occur, so those times come from
+// the hourly data, as do the day's average pressure, dew point, visibility
+// and cloud cover.
+func (om openMeteoForecast) daily(hours []DataPoint) DataBlock {
+	var b DataBlock
+	d := om.Daily
+	for i, t := range d.Time {
+		code := weatherCodes[at(d.WeatherCode, i)]
+		dp := DataPoint{
+			Time:               t,
+			Summary:            code.Summary,
+			Icon:               code.Icon,
+			SunriseTime:        valueAt(d.Sunrise, i),
+			SunsetTime:         valueAt(d.Sunset, i),
+			PrecipAccumulation: valueAt(d.PrecipitationSum, i),
+			PrecipProbability:  valueAt(d.PrecipitationProbabilityMax, i) / 100,
+			TemperatureMin:     valueAt(d.TemperatureMin, i),
+			TemperatureMax:     valueAt(d.TemperatureMax, i),
+			WindSpeed:          valueAt(d.WindSpeedMax, i),
+			WindBearing:        valueAt(d.WindDirectionDominant, i),
+			Humidity:           valueAt(d.RelativeHumidityMean, i) / 100,
+			UVIndex:            valueAt(d.UVIndexMax, i),
+		}
+		if dp.PrecipProbability > 0 {
+			dp.PrecipType = code.PrecipType
+		}
+		n := 0.0
+		for _, h := range hours {
+			if h.Time < t || h.Time >= t+86400 {
+				continue
+			}
+			if h.Temperature == dp.TemperatureMin && dp.TemperatureMinTime == 0 {
+				dp.TemperatureMinTime = h.Time
+			}
+			if h.Temperature == dp.TemperatureMax && dp.TemperatureMaxTime == 0 {
+				dp.TemperatureMaxTime = h.Time
+			}
+			dp.Pressure += h.Pressure
+			dp.DewPoint += h.DewPoint
+			dp.Visibility += h.Visibility
+			dp.CloudCover += h.CloudCover
+			n++
+		}
+		if n > 0 {
+			dp.Pressure /= n
+			dp.DewPoint /= n
+			dp.Visibility /= n
+			dp.CloudCover /= n
+		}
+		b.Data = append(b.Data, dp)
+	}
+	if len(b.Data) > 0 {
+		low, high := b.Data[0].TemperatureMax, b.Data[0].TemperatureMax
+		for _, dp := range b.Data {
+			if dp.TemperatureMax < low {
+				low = dp.TemperatureMax
+			}
+			if dp.TemperatureMax > high {
+				high = dp.TemperatureMax
+			}
+		}
+		b.Summary = fmt.Sprintf("%s today, with highs between %.0f°F and %.0f°F this week",
+			b.Data[0].Summary, low, high)
+	}
+	return b
+}
+
+// firstHours returns at most the first n data points.
+func firstHours(d []DataPoint, n int) []DataPoint {
+	if len(d) > n {
+		return d[:n]
+	}
+	return d
+}
+
+// dominantSummary returns the most frequent summary among the data points.
+func dominantSummary(d []DataPoint) string {
+	counts := make(map[string]int)
+	var summary string
+	for _, dp := range d {
+		counts[dp.Summary]++
+		if counts[dp.Summary] > counts[summary] {
+			summary = dp.Summary
+		}
+	}
+	return summary
+}
+
+// toMiles converts a visibility in meters or feet to miles.
+func toMiles(v float64, unit string) float64 {
+	if strings.HasPrefix(unit, "ft") {
+		return v / 5280
+	}
+	return v / 1609.344
+}
+
+// valueAt guards against Open-Meteo omitting or truncating an array.
+func valueAt(s []float64, i int) float64 {
+	if i < len(s) {
+		return s[i]
+	}
+	return 0
+}
+
+func at(s []int, i int) int {
+	if i < len(s) {
+		return s[i]
+	}
+	return 0
+}
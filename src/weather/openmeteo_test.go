@@ -0,0 +1,65 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenMeteoGetForecast(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("temperature_unit") != "fahrenheit" {
+			t.Errorf("request did not ask for fahrenheit: %s", r.URL)
+		}
+		http.ServeFile(w, r, "testdata/openmeteo/forecast.json")
+	}))
+	defer ts.Close()
+
+	wf, err := OpenMeteo{Address: ts.URL}.GetForecast(context.Background(), nwsCoordinates)
+	if err != nil {
+		t.Fatalf("GetForecast returned error: %v", err)
+	}
+	if wf.Timezone != "America/Los_Angeles" || wf.Offset != -7 {
+		t.Errorf("Timezone, Offset = %s, %v; want America/Los_Angeles, -7", wf.Timezone, wf.Offset)
+	}
+	if wf.Currently.Summary != "Mostly clear" || wf.Currently.Temperature != 73.4 || wf.Currently.Humidity != 0.61 {
+		t.Errorf("Currently = %+v", wf.Currently)
+	}
+
+	// Hours before the current one are dropped.
+	if len(wf.Hourly.Data) != 3 {
+		t.Fatalf("len(Hourly.Data) = %d; want 3", len(wf.Hourly.Data))
+	}
+	if wf.Hourly.Data[0].Time != 1592769600 {
+		t.Errorf("Hourly.Data[0].Time = %v; want 1592769600", wf.Hourly.Data[0].Time)
+	}
+	last := wf.Hourly.Data[2]
+	if last.PrecipType != "rain" || last.PrecipProbability != 0.2 || last.Visibility != 2 {
+		t.Errorf("Hourly.Data[2] = %+v; want rain, 0.2, 2 miles", last)
+	}
+
+	if len(wf.Daily.Data) != 2 {
+		t.Fatalf("len(Daily.Data) = %d; want 2", len(wf.Daily.Data))
+	}
+	today := wf.Daily.Data[0]
+	if today.SunriseTime != 1592743341 || today.SunsetTime != 1592795310 {
+		t.Errorf("Daily.Data[0] sunrise/sunset = %v/%v", today.SunriseTime, today.SunsetTime)
+	}
+	if today.TemperatureMax != 75 || today.TemperatureMaxTime != 1592773200 {
+		t.Errorf("Daily.Data[0] max = %v at %v; want 75 at 1592773200", today.TemperatureMax, today.TemperatureMaxTime)
+	}
+	// The rest of today's hours average 1014.75 hPa, a 59.1 °F dew point,
+	// 10 miles of visibility and 21% cloud cover.
+	if !near(today.Pressure, 1014.75, 0.001) || !near(today.DewPoint, 59.1, 0.001) ||
+		today.Visibility != 10 || !near(today.CloudCover, 0.21, 0.001) {
+		t.Errorf("Daily.Data[0] pressure/dew point/visibility/cloud cover = %v/%v/%v/%v",
+			today.Pressure, today.DewPoint, today.Visibility, today.CloudCover)
+	}
+	if wf.Currently.Visibility != 10 {
+		t.Errorf("Currently.Visibility = %v; want 10 miles", wf.Currently.Visibility)
+	}
+	if wf.Daily.Summary != "Light drizzle today, with highs between 72°F and 75°F this week" {
+		t.Errorf("Daily.Summary = %s", wf.Daily.Summary)
+	}
+}
@@ -0,0 +1,75 @@
+package weather
+
+import (
+	"context"
+	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
+	"strconv"
+)
+
+// Provider is implemented by every weather service vaporwair can report from.
+// Each provider maps its own API onto the Forecast type so the reports
+// do not need to know where the data came from.
+// Cancelling the context abandons the forecast.
+type Provider interface {
+	GetForecast(ctx context.Context, c geolocation.Coordinates) (Forecast, error)
+}
+
+// Provider names accepted in the config file.
+const (
+	DarkSkyName   = "darksky"
+	NWSName       = "nws"
+	OpenMeteoName = "openmeteo"
+	PirateName    = "pirateweather"
+)
+
+const PirateWeatherAddress = "https://api.pirateweather.net/forecast/"
+
+// PirateWeatherVersion asks Pirate Weather for its extra fields, such as smoke.
+const PirateWeatherVersion = 2
+
+// Profile configures a service that serves the Dark Sky schema, such as
+// Pirate Weather or a self-hosted compatible server. Profiles are stored
+// in the config file by name.
+type Profile struct {
+	Address string `json:"address"`
+	APIKey  string `json:"apikey"`
+	Version int    `json:"version"`
+}
+
+// DarkSky retrieves forecasts from the Dark Sky API or a compatible service.
+// A non-zero Version is passed along to services that version their schema.
+type DarkSky struct {
+	Address string
+	APIKey  string
+	Units   string
+	Version int
+}
+
+// FromProfile returns a Dark Sky provider for the named profile.
+// The Pirate Weather profile only needs an API key.
+func FromProfile(name string, p Profile) DarkSky {
+	d := DarkSky{
+		Address: p.Address,
+		APIKey:  p.APIKey,
+		Units:   DarkSkyUnits,
+		Version: p.Version,
+	}
+	if name == PirateName {
+		if d.Address == "" {
+			d.Address = PirateWeatherAddress
+		}
+		if d.Version == 0 {
+			d.Version = PirateWeatherVersion
+		}
+	}
+	return d
+}
+
+// GetForecast builds the Dark Sky URL for the coordinates and dials the API.
+func (d DarkSky) GetForecast(ctx context.Context, c geolocation.Coordinates) (Forecast, error) {
+	addr := BuildDarkSkyURL(d.Address, d.APIKey, c, d.Units)
+	if d.Version != 0 {
+		addr += "&version=" + strconv.Itoa(d.Version)
+	}
+	return GetForecast(ctx, addr)
+}
@@ -0,0 +1,43 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const pirateResponse = `{"latitude":34.0308,"longitude":-118.473,"timezone":"America/Los_Angeles",
+"currently":{"time":1592770500,"summary":"Clear","temperature":73.4,"smoke":2.1,"fireIndex":11.4},
+"hourly":{"summary":"Clear throughout the day.","data":[{"time":1592769600,"temperature":73.6,"smoke":-999,"fireIndex":12}]},
+"flags":{"units":"us","version":"V2.0.1"}}`
+
+func TestFromProfilePirateWeather(t *testing.T) {
+	d := FromProfile(PirateName, Profile{APIKey: "Key"})
+	if d.Address != PirateWeatherAddress || d.Version != PirateWeatherVersion {
+		t.Errorf("FromProfile(PirateName) = %+v; want Pirate Weather address and version", d)
+	}
+}
+
+func TestDarkSkyCompatibleServer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/forecast/Key/34.0308,-118.473" || r.URL.Query().Get("version") != "2" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		// Served without gzip, unlike Dark Sky.
+		w.Write([]byte(pirateResponse))
+	}))
+	defer ts.Close()
+
+	p := FromProfile("selfhosted", Profile{Address: ts.URL + "/forecast/", APIKey: "Key", Version: 2})
+	wf, err := p.GetForecast(context.Background(), nwsCoordinates)
+	if err != nil {
+		t.Fatalf("GetForecast returned error: %v", err)
+	}
+	if wf.Currently.Smoke == nil || *wf.Currently.Smoke != 2.1 {
+		t.Errorf("Currently.Smoke = %v; want 2.1", wf.Currently.Smoke)
+	}
+	if len(wf.Hourly.Data) != 1 || wf.Hourly.Data[0].FireIndex == nil || *wf.Hourly.Data[0].FireIndex != 12 {
+		t.Errorf("Hourly.Data = %+v; want one hour with fire index 12", wf.Hourly.Data)
+	}
+}
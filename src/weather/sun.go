@@ -0,0 +1,51 @@
+package weather
+
+import (
+	"math"
+	"time"
+)
+
+// Julian dates of the Unix epoch and of J2000.
+const (
+	julianUnixEpoch = 2440587.5
+	julianJ2000     = 2451545.0
+)
+
+// SunTimes approximates sunrise and sunset as Unix times for the calendar day
+// of d at the given coordinates, using the sunrise equation. It is accurate to
+// within a couple of minutes, which is enough for providers like the
+// National Weather Service that do not report sun times.
+// Zeroes are returned during polar day or night.
+func SunTimes(d time.Time, lat, lon float64) (float64, float64) {
+	midnight := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+	n := math.Ceil(float64(midnight.Unix())/86400 + julianUnixEpoch - julianJ2000 + 0.0008)
+	// Mean solar noon.
+	j := n - lon/360
+	// Solar mean anomaly.
+	m := math.Mod(357.5291+0.98560028*j, 360)
+	mr := radians(m)
+	// Equation of the center.
+	c := 1.9148*math.Sin(mr) + 0.02*math.Sin(2*mr) + 0.0003*math.Sin(3*mr)
+	// Ecliptic longitude.
+	l := radians(math.Mod(m+c+180+102.9372, 360))
+	transit := julianJ2000 + j + 0.0053*math.Sin(mr) - 0.0069*math.Sin(2*l)
+	// Declination of the sun.
+	sd := math.Sin(l) * math.Sin(radians(23.4397))
+	cd := math.Cos(math.Asin(sd))
+	// Hour angle, corrected for refraction and the solar disc.
+	lr := radians(lat)
+	cw := (math.Sin(radians(-0.833)) - math.Sin(lr)*sd) / (math.Cos(lr) * cd)
+	if cw < -1 || cw > 1 {
+		return 0, 0
+	}
+	w := math.Acos(cw) * 180 / math.Pi / 360
+	return julianToUnix(transit - w), julianToUnix(transit + w)
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func julianToUnix(j float64) float64 {
+	return math.Round((j - julianUnixEpoch) * 86400)
+}
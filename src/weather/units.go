@@ -0,0 +1,164 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownUnits is returned for a unit system vaporwair does not know.
+var ErrUnknownUnits = errors.New("unknown unit system")
+
+// ErrUnknownUnit is returned when a value cannot be converted between two units.
+var ErrUnknownUnit = errors.New("cannot convert")
+
+// System names the unit of each quantity in a unit system. The systems
+// follow Dark Sky's, except that US pressure is in inches of mercury.
+type System struct {
+	Temperature        string
+	WindSpeed          string
+	Distance           string
+	PrecipIntensity    string
+	PrecipAccumulation string
+	Pressure           string
+}
+
+var systems = map[Units]System{
+	US: {"°F", "mph", "mi", "in/h", "in", "inHg"},
+	SI: {"°C", "m/s", "km", "mm/h", "cm", "hPa"},
+	CA: {"°C", "km/h", "km", "mm/h", "cm", "hPa"},
+	UK: {"°C", "mph", "mi", "mm/h", "cm", "hPa"},
+}
+
+// ParseUnits reads the name of a unit system: us, si, ca, uk or auto.
+// Auto leaves the choice to the provider.
+func ParseUnits(s string) (Units, error) {
+	u := Units(strings.ToLower(strings.TrimSpace(s)))
+	switch u {
+	case "":
+		return AUTO, nil
+	case "uk2":
+		return UK, nil
+	case AUTO:
+		return u, nil
+	}
+	if _, ok := systems[u]; !ok {
+		return u, fmt.Errorf("%w: %s", ErrUnknownUnits, s)
+	}
+	return u, nil
+}
+
+// System returns the units of the system. Auto and unknown systems are US.
+func (u Units) System() System {
+	if s, ok := systems[u]; ok {
+		return s
+	}
+	return systems[US]
+}
+
+// ForecastUnits returns the unit system a forecast is in.
+func ForecastUnits(f Forecast) Units {
+	u, err := ParseUnits(f.Flags.Units)
+	if err != nil || u == AUTO {
+		return US
+	}
+	return u
+}
+
+// InUnits converts a forecast, as a provider sent it, to a unit system.
+// Providers report pressure in hectopascals whatever their system, as Dark
+// Sky does; the result has it in the system's pressure unit. Auto keeps the
+// provider's system. Every temperature field is converted, whether or not its
+// time is known. The forecast's data points are copied, not changed.
+func InUnits(f Forecast, u Units) Forecast {
+	from := ForecastUnits(f)
+	if u == AUTO || u == "" {
+		u = from
+	}
+	src, dst := from.System(), u.System()
+	src.Pressure = "hPa"
+	convert := func(d DataPoint) DataPoint {
+		d.Temperature = must(d.Temperature, src.Temperature, dst.Temperature)
+		d.TemperatureMin = must(d.TemperatureMin, src.Temperature, dst.Temperature)
+		d.TemperatureMax = must(d.TemperatureMax, src.Temperature, dst.Temperature)
+		d.ApparentTemperature = must(d.ApparentTemperature, src.Temperature, dst.Temperature)
+		d.DewPoint = must(d.DewPoint, src.Temperature, dst.Temperature)
+		d.WindSpeed = must(d.WindSpeed, src.WindSpeed, dst.WindSpeed)
+		d.Visibility = must(d.Visibility, src.Distance, dst.Distance)
+		d.PrecipIntensity = must(d.PrecipIntensity, src.PrecipIntensity, dst.PrecipIntensity)
+		d.PrecipIntensityMax = must(d.PrecipIntensityMax, src.PrecipIntensity, dst.PrecipIntensity)
+		d.PrecipAccumulation = must(d.PrecipAccumulation, src.PrecipAccumulation, dst.PrecipAccumulation)
+		d.Pressure = must(d.Pressure, src.Pressure, dst.Pressure)
+		return d
+	}
+	block := func(b DataBlock) DataBlock {
+		data := make([]DataPoint, len(b.Data))
+		for i, d := range b.Data {
+			data[i] = convert(d)
+		}
+		b.Data = data
+		return b
+	}
+	f.Currently = convert(f.Currently)
+	f.Minutely = block(f.Minutely)
+	f.Hourly = block(f.Hourly)
+	f.Daily = block(f.Daily)
+	f.Flags.Units = string(u)
+	return f
+}
+
+// scale is a unit as a multiple of the base unit of its quantity.
+type scale struct {
+	quantity string
+	factor   float64
+}
+
+// scales holds the units Convert knows, other than temperatures.
+var scales = map[string]scale{
+	"m/s":   {"speed", 1},
+	"km/h":  {"speed", 1 / 3.6},
+	"mph":   {"speed", 0.44704},
+	"kn":    {"speed", 0.514444},
+	"m":     {"distance", 1},
+	"km":    {"distance", 1000},
+	"mi":    {"distance", 1609.344},
+	"miles": {"distance", 1609.344},
+	"mm":    {"depth", 1},
+	"cm":    {"depth", 10},
+	"in":    {"depth", 25.4},
+	"mm/h":  {"rate", 1},
+	"in/h":  {"rate", 25.4},
+	"hPa":   {"pressure", 1},
+	"mb":    {"pressure", 1},
+	"kPa":   {"pressure", 10},
+	"inHg":  {"pressure", 33.8639},
+	"atm":   {"pressure", 1013.25},
+}
+
+// Convert converts a value between units of the same quantity,
+// such as °F to °C or mph to km/h.
+func Convert(v float64, from, to string) (float64, error) {
+	if from == to {
+		return v, nil
+	}
+	switch f, t := strings.TrimPrefix(from, "°"), strings.TrimPrefix(to, "°"); {
+	case f == "F" && t == "C":
+		return (v - 32) * 5 / 9, nil
+	case f == "C" && t == "F":
+		return v*9/5 + 32, nil
+	case f == t && (f == "F" || f == "C"):
+		return v, nil
+	}
+	a, ok1 := scales[from]
+	b, ok2 := scales[to]
+	if !ok1 || !ok2 || a.quantity != b.quantity {
+		return v, fmt.Errorf("%w %s to %s", ErrUnknownUnit, from, to)
+	}
+	return v * a.factor / b.factor, nil
+}
+
+// must converts between units known to be compatible.
+func must(v float64, from, to string) float64 {
+	c, _ := Convert(v, from, to)
+	return c
+}
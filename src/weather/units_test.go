@@ -0,0 +1,82 @@
+package weather
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		v        float64
+		from, to string
+		want     float64
+	}{
+		{212, "°F", "°C", 100},
+		{10, "m/s", "km/h", 36},
+		{1013.25, "hPa", "atm", 1},
+		{1, "in", "mm", 25.4},
+	}
+	for _, c := range cases {
+		if got, err := Convert(c.v, c.from, c.to); err != nil || math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("Convert(%v, %s, %s) = %v, %v; want %v", c.v, c.from, c.to, got, err, c.want)
+		}
+	}
+	if _, err := Convert(1, "mph", "hPa"); err == nil {
+		t.Error("Convert accepted mph to hPa")
+	}
+}
+
+func TestInUnits(t *testing.T) {
+	f := Forecast{
+		Currently: DataPoint{Temperature: 50, WindSpeed: 10, Pressure: 1013.25, Visibility: 10},
+		Daily:     DataBlock{Data: []DataPoint{{TemperatureMax: 212, TemperatureMaxTime: 1}}},
+		Flags:     Flags{Units: string(US)},
+	}
+	ca := InUnits(f, CA)
+	c := ca.Currently
+	if ca.Flags.Units != "ca" || math.Round(c.Temperature) != 10 || math.Round(c.WindSpeed) != 16 ||
+		c.Pressure != 1013.25 || math.Round(c.Visibility) != 16 {
+		t.Errorf("InUnits(US to CA) = %+v", c)
+	}
+	if ca.Daily.Data[0].TemperatureMax != 100 || math.Round(ca.Daily.Data[0].TemperatureMin) != -18 {
+		t.Errorf("daily = %+v; want both extremes converted", ca.Daily.Data[0])
+	}
+	if f.Daily.Data[0].TemperatureMax != 212 {
+		t.Error("InUnits changed the provider's forecast")
+	}
+	// NWS days without a night period have extremes but no times.
+	nws := Forecast{
+		Daily: DataBlock{Data: []DataPoint{{TemperatureMin: 50, TemperatureMax: 68, TemperatureMaxTime: 1}}},
+		Flags: Flags{Units: string(US)},
+	}
+	if d := InUnits(nws, SI).Daily.Data[0]; d.TemperatureMin != 10 || d.TemperatureMax != 20 {
+		t.Errorf("NWS daily in SI = %v/%v; want 10/20", d.TemperatureMin, d.TemperatureMax)
+	}
+	us := InUnits(f, AUTO)
+	if us.Currently.Temperature != 50 || math.Round(us.Currently.Pressure*100) != 2992 {
+		t.Errorf("InUnits(auto) = %+v; want US units with pressure in inHg", us.Currently)
+	}
+}
+
+func TestInUnitsOpenMeteo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/openmeteo/forecast.json")
+	}))
+	defer ts.Close()
+	wf, err := OpenMeteo{Address: ts.URL}.GetForecast(context.Background(), nwsCoordinates)
+	if err != nil {
+		t.Fatalf("GetForecast returned error: %v", err)
+	}
+	// Extremes before the current hour have no times, but are still converted.
+	si := InUnits(wf, SI)
+	for i, d := range wf.Daily.Data {
+		min, _ := Convert(d.TemperatureMin, "°F", "°C")
+		max, _ := Convert(d.TemperatureMax, "°F", "°C")
+		if got := si.Daily.Data[i]; got.TemperatureMin != min || got.TemperatureMax != max {
+			t.Errorf("day %d in SI = %v/%v; want %v/%v", i, got.TemperatureMin, got.TemperatureMax, min, max)
+		}
+	}
+}
@@ -4,11 +4,10 @@ package weather
 
 import (
 	"compress/gzip"
-	"encoding/json"
-	"fmt"
+	"context"
 	"github.com/jeff-bruemmer/vaporwair/src/dialer"
 	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
-	"log"
+	"io"
 )
 
 type Flags struct {
@@ -51,6 +50,9 @@ type DataPoint struct {
 	MoonPhase              float64 `json:"moonPhase"`
 	UVIndex                float64 `json:"uvIndex"`
 	UVIndexTime            float64 `json:"uvIndexTime"`
+	// Pirate Weather extensions, nil when the provider does not report them.
+	Smoke     *float64 `json:"smoke,omitempty"`
+	FireIndex *float64 `json:"fireIndex,omitempty"`
 }
 
 type DataBlock struct {
@@ -59,12 +61,20 @@ type DataBlock struct {
 	Data    []DataPoint `json:"data"`
 }
 
+// Alert is a severe weather alert. Severity is advisory, watch or warning,
+// as with Dark Sky. Alerts from the National Weather Service also have
+// their CAP identifier, urgency and certainty.
 type Alert struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	Time        float64 `json:"time"`
-	Expires     float64 `json:"expires"`
-	URI         string  `json:"uri"`
+	Title       string   `json:"title"`
+	Regions     []string `json:"regions,omitempty"`
+	Severity    string   `json:"severity,omitempty"`
+	Urgency     string   `json:"urgency,omitempty"`
+	Certainty   string   `json:"certainty,omitempty"`
+	Description string   `json:"description"`
+	Time        float64  `json:"time"`
+	Expires     float64  `json:"expires"`
+	URI         string   `json:"uri"`
+	Identifier  string   `json:"identifier,omitempty"`
 }
 
 type Forecast struct {
@@ -95,36 +105,43 @@ const (
 const DarkSkyAddress = "https://api.darksky.net/forecast/"
 const DarkSkyUnits = "auto"
 
-// BuildAirNowURL creates http address for dialer to call Dark Sky API.
+// BuildDarkSkyURL creates http address for dialer to call Dark Sky API,
+// or any service that serves the same schema at a different address.
 func BuildDarkSkyURL(addr string, apikey string, c geolocation.Coordinates, units string) string {
 	return addr +
 		apikey +
 		"/" +
-		c.Latitude +
+		geolocation.FormatCoordinate(c.Latitude) +
 		"," +
-		c.Longitude +
+		geolocation.FormatCoordinate(c.Longitude) +
 		"?units=" +
 		units
 }
 
 // GetForecast dials the Dark Sky API and returns a Forecast.
-func GetForecast(addr string) Forecast {
+func GetForecast(ctx context.Context, addr string) (Forecast, error) {
 	var wf Forecast
 	// Request coordinates from ip-api and specify timeout in seconds
 	// Set gzip bool to true.
-	resp, err := dialer.NetReq(addr, 5, true)
+	resp, err := dialer.NetReq(ctx, addr, 5, true)
 	if err != nil {
-		log.Fatal(err)
+		return wf, err
 	}
-	// Unzip response
 	defer resp.Body.Close()
-	gz, err := gzip.NewReader(resp.Body)
-	if err != nil {
-		fmt.Println("Error decoding gzip response from Dark Sky API.")
-		log.Fatal(err)
+	if err := dialer.CheckStatus(resp, "Dark Sky API"); err != nil {
+		return wf, err
+	}
+	var body io.Reader = resp.Body
+	// Unzip response. Compatible servers may ignore the gzip request.
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return wf, &dialer.DecodeError{Source: "Dark Sky API", Err: err}
+		}
+		defer gz.Close()
+		body = gz
 	}
 	// Decode unzipped response into weather forecast.
-	defer gz.Close()
-	json.NewDecoder(gz).Decode(&wf)
-	return wf
+	err = dialer.DecodeJSON(body, "Dark Sky API", &wf)
+	return wf, err
 }
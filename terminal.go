@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// DefaultWidth is the width of the chart when the terminal's is unknown.
+const DefaultWidth = 80
+
+// TerminalWidth returns the width of the terminal in columns: $COLUMNS
+// if set, or the width of the terminal on stdout, or DefaultWidth.
+func TerminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if n := terminalColumns(); n > 0 {
+		return n
+	}
+	return DefaultWidth
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+// terminalColumns cannot ask the terminal for its width on this platform.
+func terminalColumns() int {
+	return 0
+}
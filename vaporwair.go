@@ -1,46 +1,98 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/jeff-bruemmer/vaporwair/src/air"
+	"github.com/jeff-bruemmer/vaporwair/src/dialer"
 	"github.com/jeff-bruemmer/vaporwair/src/geolocation"
 	"github.com/jeff-bruemmer/vaporwair/src/report"
 	"github.com/jeff-bruemmer/vaporwair/src/storage"
 	"github.com/jeff-bruemmer/vaporwair/src/weather"
 	"log"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 )
 
-// Timeout, an int representing minutes, determines how long a forecast is valid.
-const Timeout = 5
+// Exit codes let scripts tell a partial report from a total failure.
+// Other fatal errors, such as an unreadable config file, exit with 1.
+const (
+	ExitPartial    = 3
+	ExitNoForecast = 4
+)
+
+// Time zones other than IANA names.
+const (
+	LocalZone    = "local"
+	ForecastZone = "forecast"
+)
+
+// Color settings
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// Alert settings, besides the names of alert services.
+const NoAlerts = "none"
+
+// Output formats
+const (
+	TextOutput = "text"
+	JSONOutput = "json"
+	CSVOutput  = "csv"
+	TSVOutput  = "tsv"
+)
 
 // Flags
 var weatherHourly bool
+var weatherChart bool
+var weatherMinutely bool
+var weatherAlerts bool
 var weatherWeek bool
 var airQuality bool
+var output string
+var columns string
+var templateName string
+var unitsName string
+var tzName string
+var clockHours int
+var color string
+var location LocationFlags
 
 // Globals
-var weatherForecast weather.Forecast
-var airForecast []air.Forecast
+var homeDir string
 var config storage.Config
+var provider weather.Provider
+var weatherName string
+var airProvider air.Provider
+var airName string
+var purpleAir *air.PurpleAir
+var cache storage.Cache
+var locator geolocation.Locator
+var tmpl *template.Template
+var units weather.Units
 
 // Variables used to sync spinner.
 var reportsReady = false
 var spinnerChan = make(chan time.Time)
 
-// Is the forecast still valid? Specify a timeout duration (in minutes) that determines whether or not
-// the forecast is still valid.
-func isValid(t time.Time, timeout float64) bool {
-	return time.Since(t).Minutes() < timeout
-}
-
 // Spinner creates a basic loading bar with zero connection to reality.
 // Its purpose is to show the user the program is running.
 // It returns a time once it finds the the reports are ready to print.
 // That time is put in a channel used to terminate the spinner.
 func Spinner(t time.Time) time.Time {
+	// Keep output for other programs clean.
+	if !TextReport() {
+		return t
+	}
 	meterInit := "\r[=>                                               ]"
 	meter := meterInit
 	for i := 0; i <= len(meterInit)-1; i++ {
@@ -51,9 +103,11 @@ func Spinner(t time.Time) time.Time {
 		time.Sleep(100 * time.Millisecond)
 		meter = strings.Replace(meter, "> ", "=>", 1)
 		fmt.Printf(meter)
-		// Loop spinner if it completes before the reports have returned
+		// Loop spinner if it completes before the reports have returned.
+		// The dialer's timeouts bound the wait.
 		if i == len(meterInit)-1 {
-			log.Fatal("There was a problem getting your forecast. Please check your internet connection.")
+			meter = meterInit
+			i = 0
 		}
 	}
 	// Clear the line.
@@ -66,90 +120,524 @@ func PrintElapsedTime(t time.Time) {
 	fmt.Printf("\rForecasts fetched in %v seconds.\n", time.Since(t).Seconds())
 }
 
+// WeatherResult holds a weather forecast, or the reason it could not be retrieved.
+// CachedAt is when a forecast served from the cache was saved.
+type WeatherResult struct {
+	Forecast weather.Forecast
+	CachedAt time.Time
+	Err      error
+}
+
+// AirResult holds an air quality forecast, or the reason it could not be retrieved.
+// CachedAt is when a forecast served from the cache was saved. The air quality
+// observed now and the readings of nearby sensors are fetched and cached
+// separately, and may arrive without a forecast.
+type AirResult struct {
+	Forecast    []air.Forecast
+	CachedAt    time.Time
+	Err         error
+	Observed    []air.Observation
+	ObservedAt  time.Time
+	ObservedErr error
+	Sensors     []air.Sensor
+	SensorsAt   time.Time
+	SensorsErr  error
+}
+
 // RunReports determines which report to run based on flags.
-// Only one report can be run at a time.
-func RunReports(f weather.Forecast, a []air.Forecast) {
+// Only one report can be run at a time. Reports render whatever data arrived,
+// noting which forecast is unavailable and why, and RunReports returns
+// the exit code for the program.
+func RunReports(w WeatherResult, a AirResult, c geolocation.Coordinates) int {
+	w.Forecast = weather.InUnits(w.Forecast, units)
+	report.SetUnits(weather.ForecastUnits(w.Forecast))
+	w.Forecast.Alerts = weather.ActiveAlerts(w.Forecast.Alerts, time.Now())
+	MarkAlerts(w)
+	var status report.Status
+	if w.Err != nil {
+		status.Weather = Explain(w.Err)
+	}
+	if a.Err != nil {
+		status.Air = Explain(a.Err)
+	}
+	if a.ObservedErr != nil {
+		status.Observed = Explain(a.ObservedErr)
+	}
+	if a.SensorsErr != nil {
+		status.Sensors = Explain(a.SensorsErr)
+	}
 	switch {
+	case tmpl != nil:
+		PrintTemplate(w, a, c, status)
+	case output == JSONOutput:
+		PrintJSON(w, a, c, status)
+	case output == CSVOutput, output == TSVOutput:
+		PrintSeries(w, status)
+	case weatherHourly && w.Err != nil, weatherWeek && w.Err != nil, weatherChart && w.Err != nil,
+		weatherMinutely && w.Err != nil, weatherAlerts && w.Err != nil:
+		report.Unavailable("Weather", status.Weather)
+	case airQuality && a.Err != nil && len(a.Observed) == 0 && len(a.Sensors) == 0:
+		report.Unavailable("Air quality", status.Air)
 	case weatherHourly:
-		report.WeatherHourly(f, a)
+		report.WeatherHourly(w.Forecast, a.Forecast)
+	case weatherChart:
+		report.Chart(w.Forecast, a.Forecast, TerminalWidth())
+	case weatherAlerts:
+		report.Alerts(w.Forecast, TerminalWidth())
+	case weatherMinutely:
+		report.Minutely(w.Forecast, a.Forecast, TerminalWidth())
 	case weatherWeek:
-		report.WeatherWeek(f, a)
+		report.WeatherWeek(w.Forecast, a.Forecast)
 	case airQuality:
-		report.AirQuality(f, a)
+		if a.Err != nil {
+			report.Unavailable("Air quality forecast", status.Air)
+		}
+		report.AirQuality(w.Forecast, a.Forecast, a.Observed, a.Sensors)
 	default:
-		report.Summary(f, a)
+		report.Summary(w.Forecast, a.Forecast, a.Observed, a.Sensors, status)
+	}
+	switch {
+	case w.Err != nil && a.Err != nil:
+		return ExitNoForecast
+	case w.Err != nil, a.Err != nil:
+		return ExitPartial
+	}
+	return 0
+}
+
+// ReportName names the report selected by flags.
+func ReportName() string {
+	switch {
+	case weatherHourly:
+		return report.HourlyReport
+	case weatherChart:
+		return report.ChartReport
+	case weatherMinutely:
+		return report.MinutelyReport
+	case weatherAlerts:
+		return report.AlertsReport
+	case weatherWeek:
+		return report.WeekReport
+	case airQuality:
+		return report.AirReport
+	}
+	return report.SummaryReport
+}
+
+// MarkAlerts highlights weather alerts not shown before, and remembers
+// them for the next run. Only the reports that show alerts mark them seen.
+func MarkAlerts(w WeatherResult) {
+	if w.Err != nil {
+		return
+	}
+	switch ReportName() {
+	case report.SummaryReport, report.AlertsReport:
+	default:
+		return
+	}
+	path := homeDir + storage.AlertsFileName
+	seen, err := storage.LoadSeenAlerts(path)
+	if err != nil {
+		// Start over, rather than hide the alerts.
+		seen = storage.SeenAlerts{}
+	}
+	report.SetNewAlerts(seen.Update(w.Forecast.Alerts, time.Now()))
+	if err := storage.SaveSeenAlerts(path, seen); err != nil {
+		fmt.Fprintln(os.Stderr, "Error saving weather alerts.\n", err)
+	}
+}
+
+// PrintJSON prints the selected report as a JSON document for other programs.
+func PrintJSON(w WeatherResult, a AirResult, c geolocation.Coordinates, s report.Status) {
+	sources := []report.Source{
+		report.NewSource(weatherName, report.WeatherSource, w.CachedAt, s.Weather),
+		report.NewSource(airName, report.AirSource, a.CachedAt, s.Air),
+		report.NewSource(airName+storage.ObservedSuffix, report.ObservationSource, a.ObservedAt, s.Observed),
+	}
+	if purpleAir != nil {
+		sources = append(sources, report.NewSource(air.PurpleAirName, report.SensorSource, a.SensorsAt, s.Sensors))
+	}
+	d := report.JSON(ReportName(), w.Forecast, a.Forecast, a.Observed, a.Sensors, ReportLocation(c), sources, time.Now())
+	if err := report.WriteJSON(os.Stdout, d); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ReportLocation describes the coordinates for JSON documents and templates.
+func ReportLocation(c geolocation.Coordinates) report.Location {
+	return report.Location{
+		Latitude:  c.Latitude,
+		Longitude: c.Longitude,
+		City:      c.City,
+		Zip:       c.Zip,
+	}
+}
+
+// TextReport reports whether one of the built-in reports is printed
+// as text, rather than for a template or another program.
+func TextReport() bool {
+	return output == TextOutput && tmpl == nil
+}
+
+// PrintTemplate renders the user's report template.
+func PrintTemplate(w WeatherResult, a AirResult, c geolocation.Coordinates, s report.Status) {
+	d := report.NewTemplateData(ReportName(), w.Forecast, a.Forecast, a.Observed, a.Sensors, ReportLocation(c), s, time.Now())
+	if err := report.Render(os.Stdout, tmpl, d); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// PrintSeries prints the minutely, hourly or weekly forecast as CSV or TSV,
+// one row per data point. Errors go to stderr to keep the table clean.
+func PrintSeries(w WeatherResult, s report.Status) {
+	if w.Err != nil {
+		fmt.Fprintln(os.Stderr, "Weather unavailable:", s.Weather)
+		return
+	}
+	names := config.Columns
+	if columns != "" {
+		names = strings.Split(columns, ",")
+	}
+	cs, err := report.SelectColumns(names)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data := w.Forecast.Hourly.Data
+	switch {
+	case weatherMinutely:
+		data = w.Forecast.Minutely.Data
+	case weatherWeek:
+		data = w.Forecast.Daily.Data
+	}
+	comma := ','
+	if output == TSVOutput {
+		comma = '\t'
+	}
+	if err := report.Series(os.Stdout, w.Forecast, data, cs, comma); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// TimeZone returns the time zone to show a forecast's times in: the local
+// time zone, the forecast's, or the one with an IANA name.
+func TimeZone(name string, w weather.Forecast) (*time.Location, error) {
+	switch name {
+	case LocalZone:
+		return time.Local, nil
+	case ForecastZone, "":
+		return report.ForecastZone(w), nil
+	}
+	return time.LoadLocation(name)
+}
+
+// CheckClock validates a clock setting of 12 or 24 hours. Zero is the default.
+func CheckClock(hours int) error {
+	if hours != 0 && hours != 12 && hours != 24 {
+		return errors.New("the clock must be 12 or 24 hours, not " + strconv.Itoa(hours))
+	}
+	return nil
+}
+
+// UseColor reports whether to color the text reports. Auto colors them
+// only on a terminal, unless NO_COLOR is set (https://no-color.org).
+func UseColor(setting string) bool {
+	switch setting {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// CheckColor validates a color setting. Empty is auto.
+func CheckColor(setting string) error {
+	switch setting {
+	case "", ColorAuto, ColorAlways, ColorNever:
+		return nil
 	}
+	return errors.New("color must be auto, always or never, not " + setting)
+}
+
+// Located holds the result of a geolocation lookup made in the background.
+type Located struct {
+	Coordinates geolocation.Coordinates
+	Err         error
 }
 
 // GetCoordinates retrieves user's current coordinates via IP address
-// and the IP-API.
-func GetCoordinates() geolocation.Coordinates {
-	// Get geolocation data.
-	geoData := geolocation.GetGeoData(geolocation.IPAPIAddress)
-	// Format coordinates and compose URLs for API calls.
-	return geolocation.FormatCoordinates(geoData)
+// and the configured geolocation services.
+func GetCoordinates(ctx context.Context) (geolocation.Coordinates, error) {
+	return locator.Locate(ctx)
 }
 
-func PrintSpaceTime(t, t1 time.Time, c geolocation.Coordinates) {
-	PrintElapsedTime(t1)
-	fmt.Println(t.Format("Mon Jan 2 15:04:05 MST 2006"))
-	fmt.Println(c.City, c.Zip, "|", c.Latitude, ",", c.Longitude)
+// Explain turns errors returned by the library packages into
+// messages for the user.
+func Explain(err error) string {
+	var se *dialer.StatusError
+	var de *dialer.DecodeError
+	var ue *url.Error
+	switch {
+	case errors.Is(err, dialer.ErrTimeout):
+		return "The request timed out. Please check your internet connection."
+	case errors.Is(err, dialer.ErrQuotaExceeded):
+		return "The API quota for your key has been used up. Please try again later."
+	case errors.Is(err, geolocation.ErrNoLocation):
+		return "The geolocation service could not resolve your coordinates."
+	case errors.Is(err, geolocation.ErrInvalidCoordinates):
+		return "Invalid coordinates: " + err.Error() + "."
+	case errors.Is(err, geolocation.ErrUnknownZip):
+		if config.OfflineZip {
+			return "There is " + err.Error() + " in the gazetteer. Remove offlinezip from the config file to look it up online."
+		}
+		return "There is " + err.Error() + "."
+	case errors.Is(err, weather.ErrUnknownUnits):
+		return "The config file sets an " + err.Error() + ". Use us, si, ca, uk or auto."
+	case errors.Is(err, storage.ErrUnknownTheme):
+		return "There is " + err.Error() + ". Themes are saved in ~/.vaporwair/themes as NAME.json."
+	case errors.Is(err, storage.ErrUnknownTemplate):
+		return "There is " + err.Error() + ". Templates are saved in ~/.vaporwair/templates as NAME.tmpl."
+	case errors.Is(err, storage.ErrUnknownPlace):
+		return "There is " + err.Error() + ". Run vaporwair locations to list saved places."
+	case errors.Is(err, geolocation.ErrNoMatch):
+		return "There is " + err.Error() + `. Try adding the state or country, e.g. "Portland, OR".`
+	case errors.Is(err, storage.ErrCorrupt):
+		return "A file in the vaporwair directory is corrupt: " + err.Error()
+	case errors.As(err, &se):
+		return "The " + se.Source + " returned an error (" + se.Status + "). Please check your API keys."
+	case errors.As(err, &de):
+		return "The " + de.Source + " returned a response vaporwair could not read."
+	case errors.As(err, &ue):
+		// Avoid printing API keys in the address.
+		if u, perr := url.Parse(ue.URL); perr == nil {
+			return "Could not reach " + u.Host + ". Please check your internet connection."
+		}
+	}
+	return "There was a problem getting your forecast: " + err.Error()
+}
+
+// WeatherProvider returns the weather service selected in the config file.
+// The National Weather Service is the default. Neither it nor Open-Meteo
+// requires an API key.
+func WeatherProvider(c storage.Config) weather.Provider {
+	switch c.WeatherProvider {
+	case weather.OpenMeteoName:
+		return weather.OpenMeteo{Address: weather.OpenMeteoAddress}
+	case weather.DarkSkyName:
+		return weather.DarkSky{
+			Address: weather.DarkSkyAddress,
+			APIKey:  c.DarkSkyAPIKey,
+			Units:   string(units),
+		}
+	case weather.NWSName, "":
+		return weather.NWS{Address: weather.NWSAddress}
+	}
+	// Pirate Weather and self-hosted servers speak the Dark Sky schema.
+	p, ok := c.Profiles[c.WeatherProvider]
+	if !ok {
+		log.Fatal("The weather provider ", c.WeatherProvider, " has no profile in the config file.")
+	}
+	d := weather.FromProfile(c.WeatherProvider, p)
+	d.Units = string(units)
+	return d
+}
+
+// WithAlerts adds the alerts of the service selected in the config file to
+// the provider's forecasts. The National Weather Service's are the default.
+func WithAlerts(p weather.Provider, c storage.Config) weather.Provider {
+	switch c.Alerts {
+	case weather.NWSName, "":
+		return weather.WithAlerts{Provider: p, Alerts: weather.NWSAlerts{Address: weather.NWSAddress}}
+	case NoAlerts:
+		return p
+	}
+	log.Fatal("The alert service ", c.Alerts, " is not supported.")
+	return p
+}
+
+// AirProvider returns the air quality service selected in the config file.
+// AirNow, the default, only covers the United States; OpenAQ covers the world
+// but only reports what is observed now. Both require an API key.
+func AirProvider(c storage.Config) air.Provider {
+	switch c.AirProvider {
+	case air.AirNowName, "":
+		return air.AirNow{
+			ForecastAddress:    air.AirNowAddress,
+			ObservationAddress: air.AirNowObservationAddress,
+			APIKey:             c.AirNowAPIKey,
+		}
+	case air.OpenAQName:
+		return air.OpenAQ{Address: air.OpenAQAddress, APIKey: c.OpenAQAPIKey, MaxAge: air.OpenAQMaxAge}
+	}
+	log.Fatal("The air quality provider ", c.AirProvider, " is not supported.")
+	return nil
+}
+
+// PurpleAir returns the PurpleAir sensors selected in the config file,
+// or nil if there are none.
+func PurpleAir(c storage.Config) *air.PurpleAir {
+	if c.PurpleAir == nil || !c.PurpleAir.Configured() {
+		return nil
+	}
+	return &air.PurpleAir{
+		Address: air.PurpleAirAddress,
+		APIKey:  c.PurpleAir.APIKey,
+		Sensors: c.PurpleAir.Sensors,
+		Box:     c.PurpleAir.Box,
+	}
+}
+
+// Locator returns the geolocation services selected in the config file:
+// gpsd, if configured, then the IP geolocation services.
+func Locator(c storage.Config) geolocation.Locator {
+	names := c.Locators
+	if len(names) == 0 {
+		names = geolocation.DefaultLocators
+	}
+	chain := geolocation.Chain{Race: c.RaceLocators}
+	for _, name := range names {
+		switch name {
+		case geolocation.IPAPIName:
+			chain.Locators = append(chain.Locators, geolocation.IPAPI{Address: geolocation.IPAPIAddress})
+		case geolocation.IPInfoName:
+			chain.Locators = append(chain.Locators, geolocation.IPInfo{Address: geolocation.IPInfoAddress, Token: c.IPInfoToken})
+		case geolocation.IPWhoisName:
+			chain.Locators = append(chain.Locators, geolocation.IPWhois{Address: geolocation.IPWhoisAddress})
+		default:
+			log.Fatal("The geolocation service ", name, " is not supported.")
+		}
+	}
+	// A GPS fix is more precise than any IP address lookup.
+	if c.GPSD != "" {
+		gps := geolocation.GPSD{Address: c.GPSD}
+		return geolocation.Chain{Locators: []geolocation.Locator{gps, chain}}
+	}
+	return chain
+}
+
+// GetWeather retrieves the weather forecast for the coordinates from the cache,
+// or from the configured provider if there is no fresh forecast for the location.
+func GetWeather(ctx context.Context, c geolocation.Coordinates) WeatherResult {
+	var wf weather.Forecast
+	if saved, err := cache.Get(weatherName, c, &wf); err == nil {
+		return WeatherResult{Forecast: wf, CachedAt: saved}
+	}
+	wf, err := provider.GetForecast(ctx, c)
+	return WeatherResult{Forecast: wf, Err: err}
 }
 
-func RunReportsForFirstTime(c geolocation.Coordinates, t time.Time) (weather.Forecast, []air.Forecast) {
-	dsURL := weather.BuildDarkSkyURL(weather.DarkSkyAddress, config.DarkSkyAPIKey, c, weather.DarkSkyUnits)
-	// build AirNowURL
-	anURL := air.BuildAirNowURL(air.AirNowAddress, c, t.Format("2006-01-02"), config.AirNowAPIKey)
-	weatherChan := make(chan weather.Forecast)
-	airChan := make(chan []air.Forecast)
+// GetAir retrieves the air quality forecast and current observations for the
+// coordinates from the cache, or from the configured provider if there are
+// none fresh for the location. Observations go stale sooner than forecasts.
+// Nearby sensors are read at the same time.
+func GetAir(ctx context.Context, c geolocation.Coordinates, t time.Time) AirResult {
+	var r AirResult
+	done := make(chan bool, 2)
 	go func() {
-		weatherChan <- weather.GetForecast(dsURL)
+		r.Sensors, r.SensorsAt, r.SensorsErr = GetSensors(ctx, c)
+		done <- true
 	}()
 	go func() {
-		airChan <- air.GetForecast(anURL)
+		var o []air.Observation
+		if saved, err := cache.Get(airName+storage.ObservedSuffix, c, &o); err == nil {
+			r.Observed, r.ObservedAt = o, saved
+		} else {
+			r.Observed, r.ObservedErr = airProvider.GetObservations(ctx, c)
+		}
+		done <- true
 	}()
-	// Wait for API calls to return and run reports.
-	weatherForecast = <-weatherChan
-	close(weatherChan)
-	airForecast = <-airChan
-	close(airChan)
-	reportsReady = true
-	t1 := <-spinnerChan
-	close(spinnerChan)
-	PrintSpaceTime(t, t1, c)
-	RunReports(weatherForecast, airForecast)
-	report.TW.Flush()
-	return weatherForecast, airForecast
+	var af []air.Forecast
+	if saved, err := cache.Get(airName, c, &af); err == nil {
+		r.Forecast, r.CachedAt = af, saved
+	} else {
+		r.Forecast, r.Err = airProvider.GetForecast(ctx, c, t)
+	}
+	<-done
+	<-done
+	return r
 }
 
-// Saves forecast in Vaporwair home directory for caching.
-func SaveForecasts(homeDir string, coordinates geolocation.Coordinates, weather weather.Forecast, air []air.Forecast) {
-	// Update last api call
-	storage.UpdateLastCall(coordinates, homeDir+storage.SavedCallFileName)
+// GetSensors retrieves the readings of the configured sensors near the
+// coordinates from the cache, or from PurpleAir if there are none fresh
+// for the location. The time is when cached readings were saved.
+func GetSensors(ctx context.Context, c geolocation.Coordinates) ([]air.Sensor, time.Time, error) {
+	if purpleAir == nil {
+		return nil, time.Time{}, nil
+	}
+	var s []air.Sensor
+	if saved, err := cache.Get(air.PurpleAirName, c, &s); err == nil {
+		return s, saved, nil
+	}
+	s, err := purpleAir.GetSensors(ctx, c)
+	return s, time.Time{}, err
+}
+
+// Fetch asynchronously retrieves the weather and air quality forecasts
+// for the coordinates. The channels are buffered so abandoned calls can finish.
+func Fetch(ctx context.Context, c geolocation.Coordinates, t time.Time) (chan WeatherResult, chan AirResult) {
+	weatherChan := make(chan WeatherResult, 1)
+	airChan := make(chan AirResult, 1)
+	go func() {
+		weatherChan <- GetWeather(ctx, c)
+	}()
+	go func() {
+		airChan <- GetAir(ctx, c, t)
+	}()
+	return weatherChan, airChan
+}
 
-	// Save forecasts for next call
-	storage.SaveWeatherForecast(homeDir+storage.SavedWeatherFileName, weatherForecast)
-	storage.SaveAirForecast(homeDir+storage.SavedAirFileName, airForecast)
+func PrintSpaceTime(t, t1 time.Time, c geolocation.Coordinates) {
+	PrintElapsedTime(t1)
+	fmt.Println(report.FormatDateTime(t))
+	fmt.Println(c.City, c.Zip, "|", geolocation.FormatCoordinate(c.Latitude), ",", geolocation.FormatCoordinate(c.Longitude))
 }
 
-// CaptureAPIKeys prompts users for Dark Sky and Air Now API keys
-// and saves thems in a config file.
+// SaveForecasts caches newly fetched forecasts for the location.
+// Forecasts that came from the cache or failed to arrive are skipped.
+func SaveForecasts(c geolocation.Coordinates, w WeatherResult, a AirResult) {
+	if w.Err == nil && w.CachedAt.IsZero() {
+		if err := cache.Put(weatherName, c, w.Forecast); err != nil {
+			fmt.Println("Error saving weather forecast.\n", err)
+		}
+	}
+	if a.Err == nil && a.CachedAt.IsZero() {
+		if err := cache.Put(airName, c, a.Forecast); err != nil {
+			fmt.Println("Error saving air forecast.\n", err)
+		}
+	}
+	if a.ObservedErr == nil && a.ObservedAt.IsZero() {
+		if err := cache.Put(airName+storage.ObservedSuffix, c, a.Observed); err != nil {
+			fmt.Println("Error saving air quality observations.\n", err)
+		}
+	}
+	if purpleAir != nil && a.SensorsErr == nil && a.SensorsAt.IsZero() {
+		if err := cache.Put(air.PurpleAirName, c, a.Sensors); err != nil {
+			fmt.Println("Error saving sensor readings.\n", err)
+		}
+	}
+}
+
+// CaptureAPIKeys prompts users for an Air Now API key and saves it in a config file.
+// The default weather provider needs no key; Dark Sky users add theirs to the config file.
 func CaptureAPIKeys(homeDir string) {
-	DSAPIKey := storage.Capture("Enter Dark Sky API key: ")
 	ANAPIKey := storage.Capture("Enter Air Now API key: ")
-	err := storage.CreateConfig(homeDir, DSAPIKey, ANAPIKey)
+	err := storage.CreateConfig(homeDir, "", ANAPIKey)
 	if err != nil {
 		log.Fatal("There was a problem saving your APIkeys. Try again.")
 	}
 }
 
 // CleanPrintSave closes open air, weather, and spinner channels; prints reports;
-// and saves the forecasts for future reporting.
-func CleanPrintSave(weatherChan chan weather.Forecast, airChan chan []air.Forecast, t time.Time, c geolocation.Coordinates, homeDir string) {
+// and saves the forecasts for future reporting. It returns the exit code for the program.
+func CleanPrintSave(weatherChan chan WeatherResult, airChan chan AirResult, t time.Time, c geolocation.Coordinates) int {
 	// Wait for forecasts, then close channels.
-	weatherForecast = <-weatherChan
+	w := <-weatherChan
 	close(weatherChan)
-	airForecast = <-airChan
+	a := <-airChan
 	close(airChan)
 
 	// Stop Spinner
@@ -158,44 +646,94 @@ func CleanPrintSave(weatherChan chan weather.Forecast, airChan chan []air.Foreca
 	close(spinnerChan)
 
 	// Print time and geodata, then print reports.
-	PrintSpaceTime(t, t1, c)
-	RunReports(weatherForecast, airForecast)
+	loc, err := TimeZone(tzName, w.Forecast)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report.SetTimeZone(loc)
+	if TextReport() {
+		PrintSpaceTime(t, t1, c)
+	}
+	code := RunReports(w, a, c)
 	report.TW.Flush()
 
 	// Save forecasts
-	SaveForecasts(homeDir, c, weatherForecast, airForecast)
-	return
-
+	SaveForecasts(c, w, a)
+	return code
 }
 
 // Assign commandline flags.
 func init() {
 	flag.BoolVar(&weatherHourly, "h", false, "Prints weather forecast hour by hour.")
+	flag.BoolVar(&weatherChart, "c", false, "Charts temperature and precipitation over the next 48 hours.")
+	flag.BoolVar(&weatherMinutely, "m", false, "Charts precipitation minute by minute over the next hour.")
+	flag.BoolVar(&weatherAlerts, "alerts", false, "Prints the severe weather alerts in effect.")
 	flag.BoolVar(&weatherWeek, "w", false, "Prints daily weather forecast for the next week.")
 	flag.BoolVar(&airQuality, "a", false, "Prints air quality forecast.")
+	flag.StringVar(&output, "o", TextOutput, "Output format: text, json, or csv or tsv with -m, -h or -w.")
+	flag.StringVar(&templateName, "template", "", "Template file, or name of a template in ~/.vaporwair/templates, to print the report with.")
+	flag.StringVar(&unitsName, "units", "", "Unit system: us, si, ca, uk, or auto to let the weather provider choose.")
+	flag.StringVar(&tzName, "tz", "", "Time zone to show times in: local, forecast (default), or an IANA name such as Europe/Paris.")
+	flag.IntVar(&clockHours, "clock", 0, "Show times on a 12 or 24-hour clock.")
+	flag.StringVar(&color, "color", "", "Color the reports: auto (default), always or never.")
+	flag.StringVar(&columns, "columns", "", "Comma-separated fields to export as CSV or TSV.")
+	location.Register(flag.CommandLine)
+}
+
+// CheckFlags validates the unit system, time zone, clock, color setting,
+// output format and columns to export.
+func CheckFlags() error {
+	if _, err := weather.ParseUnits(unitsName); err != nil {
+		return err
+	}
+	if _, err := TimeZone(tzName, weather.Forecast{}); err != nil {
+		return err
+	}
+	if err := CheckClock(clockHours); err != nil {
+		return err
+	}
+	if err := CheckColor(color); err != nil {
+		return err
+	}
+	if templateName != "" && output != TextOutput {
+		return errors.New("-template cannot be combined with -o " + output)
+	}
+	switch output {
+	case TextOutput, JSONOutput:
+		return nil
+	case CSVOutput, TSVOutput:
+		if !weatherMinutely && !weatherHourly && !weatherWeek {
+			return errors.New(output + " output needs -m, -h or -w")
+		}
+		if columns == "" {
+			return nil
+		}
+		_, err := report.SelectColumns(strings.Split(columns, ","))
+		return err
+	}
+	return errors.New("unknown output format " + output)
 }
 
 // The main function is large for a Go program, but it provides a good
 // overview of the program's execution.
 func main() {
 	t := time.Now()
-	// Start call to IP-API in case previously used coordinates either
-	// do not exist or are invalid.
-	geoChan := make(chan geolocation.GeoData)
-	go func() {
-		geoChan <- geolocation.GetGeoData(geolocation.IPAPIAddress)
-	}()
-
-	// Start Spinner
-	go func() {
-		spinnerChan <- Spinner(t)
-	}()
+	ctx := context.Background()
 
 	// Parse flags to determine which report to run.
 	flag.Parse()
+	given, err := location.Given(flag.CommandLine)
+	if err == nil {
+		err = CheckFlags()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
 
 	// First get home directory for user.
-	homeDir, err := storage.GetHomeDir()
+	homeDir, err = storage.GetHomeDir()
 	// If the home directory could not be determined, bail.
 	if err != nil {
 		log.Fatal("Unable to determine home directory.")
@@ -204,6 +742,17 @@ func main() {
 	// Identify or create vaporwair directory.
 	storage.CreateVaporwairDir(homeDir + storage.VaporwairDir)
 
+	// Parse the report template before waiting on any forecasts.
+	if templateName != "" {
+		text, err := storage.LoadTemplate(homeDir, templateName)
+		if err != nil {
+			log.Fatal(Explain(err))
+		}
+		if tmpl, err = report.NewTemplate(templateName, text); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// Check if configuration file with API keys exists.
 	cf := homeDir + storage.ConfigFileName
 	configExists, _ := storage.Exists(cf)
@@ -213,95 +762,150 @@ func main() {
 		CaptureAPIKeys(homeDir)
 	}
 
-	// Load API keys
-	config = storage.GetConfig(cf)
-
-	// Channels to store calls with newly confirmed coordinates
-	airChan := make(chan []air.Forecast)
-	weatherChan := make(chan weather.Forecast)
-
-	// Load previous call metadata to determine if call is still valid.
-	pc, err := storage.LoadCallInfo(homeDir + storage.SavedCallFileName)
+	// Load API keys and select the weather provider and geolocation services.
+	config, err = storage.GetConfig(cf)
 	if err != nil {
-		// If not, run the reports for the first time.
-		coordinates := GetCoordinates()
-		weatherForecast, airForecast = RunReportsForFirstTime(coordinates, t)
-		SaveForecasts(homeDir, coordinates, weatherForecast, airForecast)
-		return
+		log.Fatal(Explain(err))
 	}
-
-	// If saved forecasts are found, check if the call has expired.
-	valid := isValid(pc.Time, Timeout)
-
-	// If the previous air and weather forecasts are still valid
-	// i.e. they were made within the timeout period suppplied to the isValid function
-	// (presumably from the same location), print forecast report and return.
-	if valid {
-		// Load previous weather forecast from disk.
-		pwf, err := storage.LoadSavedWeather(homeDir + storage.SavedWeatherFileName)
-		if err != nil {
-			fmt.Println("No previous weather forecast found.")
+	if _, err := report.SelectColumns(config.Columns); err != nil {
+		log.Fatal("Config columns: ", err)
+	}
+	if unitsName == "" {
+		unitsName = config.Units
+	}
+	if units, err = weather.ParseUnits(unitsName); err != nil {
+		log.Fatal(Explain(err))
+	}
+	if tzName == "" {
+		tzName = config.TimeZone
+	}
+	if _, err := TimeZone(tzName, weather.Forecast{}); err != nil {
+		log.Fatal("Config timezone: ", err)
+	}
+	if clockHours == 0 {
+		clockHours = config.Clock
+	}
+	if err := CheckClock(clockHours); err != nil {
+		log.Fatal("Config clock: ", err)
+	}
+	report.SetClock(clockHours == 12)
+	if color == "" {
+		color = config.Color
+	}
+	if err := CheckColor(color); err != nil {
+		log.Fatal("Config color: ", err)
+	}
+	if TextReport() && UseColor(color) {
+		theme := report.Theme{}
+		if config.Theme != "" {
+			if theme, err = storage.LoadTheme(homeDir, config.Theme); err != nil {
+				log.Fatal(Explain(err))
+			}
 		}
-		// Load previous air forecast from disk.
-		paf, err := storage.LoadSavedAir(homeDir + storage.SavedAirFileName)
-		if err != nil {
-			fmt.Println("No previous air forecast found.")
-			paf = <-airChan
+		if err := report.SetColor(theme); err != nil {
+			log.Fatal("Theme ", config.Theme, ": ", err)
 		}
-		reportsReady = true
-		t1 := <-spinnerChan
-		PrintSpaceTime(t, t1, pc.Coordinates)
-		RunReports(pwf, paf)
-		report.TW.Flush()
-		return
+	}
+	provider = WithAlerts(WeatherProvider(config), config)
+	weatherName = config.WeatherProvider
+	if weatherName == "" {
+		weatherName = weather.NWSName
+	}
+	airProvider = AirProvider(config)
+	airName = config.AirProvider
+	if airName == "" {
+		airName = air.AirNowName
+	}
+	purpleAir = PurpleAir(config)
+	locator = Locator(config)
+	cache = storage.NewCache(homeDir+storage.CacheDir, config.Cache)
+	if config.MatchRadius > 0 {
+		cache.MatchRadius = config.MatchRadius
+	}
+	if config.Retries > 0 {
+		dialer.DefaultRetry.Attempts = config.Retries + 1
 	}
 
-	// While waiting for the coordinates to return form the IP-API,
-	// assume user has not changed coordinates since last weather check
-	// and make optimistic call to APIs using saved coordinates.
-	odsURL := weather.BuildDarkSkyURL(weather.DarkSkyAddress, config.DarkSkyAPIKey, pc.Coordinates, weather.DarkSkyUnits)
-	oanURL := air.BuildAirNowURL(air.AirNowAddress, pc.Coordinates, t.Format("2006-01-02"), config.AirNowAPIKey)
+	// Manage saved places, then exit.
+	if flag.Arg(0) == "locations" {
+		if err := Locations(ctx, homeDir, flag.Args()[1:]); err != nil {
+			log.Fatal(Explain(err))
+		}
+		return
+	}
 
-	// optimistic channels
-	ow := make(chan weather.Forecast)
-	oa := make(chan []air.Forecast)
+	// A location given on the command line or a default place skips
+	// the IP lookup. Given locations are resolved before the spinner starts,
+	// since an ambiguous place name prompts the user.
+	places, err := storage.LoadPlaces(homeDir + storage.PlacesFileName)
+	if err != nil {
+		log.Fatal(Explain(err))
+	}
+	chosen := given || places.Default != ""
+	geoChan := make(chan Located, 1)
+	switch {
+	case given:
+		c, err := location.Coordinates(ctx, places)
+		geoChan <- Located{c, err}
+	case places.Default != "":
+		c, err := places.Get(places.Default)
+		geoChan <- Located{c, err}
+	default:
+		// Start looking up the IP address in case previously used
+		// coordinates either do not exist or are invalid.
+		go func() {
+			c, err := GetCoordinates(ctx)
+			geoChan <- Located{c, err}
+		}()
+	}
 
+	// Start Spinner
 	go func() {
-		ow <- weather.GetForecast(odsURL)
-	}()
-	go func() {
-		oa <- air.GetForecast(oanURL)
+		spinnerChan <- Spinner(t)
 	}()
 
-	// Get geolocation data from channel and extract coordinates.
-	geoData := <-geoChan
-	coordinates := geolocation.FormatCoordinates(geoData)
+	// Find the location the user last checked. If there is none, or the user
+	// chose a location, wait for it, then fetch forecasts.
+	last, err := cache.Latest()
+	if err != nil || chosen {
+		located := <-geoChan
+		if located.Err != nil {
+			log.Fatal(Explain(located.Err))
+		}
+		weatherChan, airChan := Fetch(ctx, located.Coordinates, t)
+		os.Exit(CleanPrintSave(weatherChan, airChan, t, located.Coordinates))
+	}
 
-	// If current coordinates match previous coordinates, the optimistic API calls
+	// While waiting for the coordinates to return form the IP-API,
+	// assume user has not changed location since last weather check
+	// and make optimistic calls using the last coordinates. Forecasts
+	// still fresh in the cache are served from disk.
+	// The optimistic calls are cancelled if the user turns out to have moved.
+	optimistic, cancelOptimistic := context.WithCancel(ctx)
+	defer cancelOptimistic()
+	ow, oa := Fetch(optimistic, last, t)
+
+	// Get coordinates from channel. If the geolocation service fails,
+	// fall back on the last coordinates and the optimistic calls.
+	located := <-geoChan
+	coordinates := located.Coordinates
+	if located.Err != nil {
+		coordinates = last
+	}
+
+	// If current coordinates are near the last coordinates, the optimistic calls
 	// are valid, no need to make new calls. Clean up, print reports, save forecasts,
-	// and return.
-	if coordinates.Latitude == pc.Coordinates.Latitude &&
-		coordinates.Longitude == pc.Coordinates.Longitude {
-		CleanPrintSave(ow, oa, t, coordinates, homeDir)
-		return
-	} else {
-		// If coordinates returned by IP-API call differ from coordinates in saved forecast,
-		// user is in a new location, and calls with the updated coordinates need to be made.
+	// and exit. The forecasts are for the last location, so report that one.
+	if geolocation.Near(coordinates, last, cache.MatchRadius) {
+		os.Exit(CleanPrintSave(ow, oa, t, last))
+	}
 
-		// Build URLs.
-		dsURL := weather.BuildDarkSkyURL(weather.DarkSkyAddress, config.DarkSkyAPIKey, coordinates, weather.DarkSkyUnits)
-		anURL := air.BuildAirNowURL(air.AirNowAddress, coordinates, t.Format("2006-01-02"), config.AirNowAPIKey)
+	// If coordinates returned by IP-API call are farther away,
+	// user is in a new location. Use forecasts cached for it, if still fresh,
+	// and call the APIs for the rest.
+	cancelOptimistic()
+	weatherChan, airChan := Fetch(ctx, coordinates, t)
 
-		// Asynchronously make calls to Dark Sky and Airnow with confirmed coordinates
-		go func() {
-			weatherChan <- weather.GetForecast(dsURL)
-		}()
-		go func() {
-			airChan <- air.GetForecast(anURL)
-		}()
-
-		// Wait for forecasts to return, then clean up, print reports, save forecasts, and return.
-		CleanPrintSave(weatherChan, airChan, t, coordinates, homeDir)
-		return
-	}
+	// Wait for forecasts to return, then clean up, print reports, save forecasts, and exit.
+	os.Exit(CleanPrintSave(weatherChan, airChan, t, coordinates))
 }